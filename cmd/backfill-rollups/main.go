@@ -0,0 +1,22 @@
+// Command backfill-rollups rebuilds the hourly and daily click rollups and the
+// click counts of the links from the raw clicks table. Run it from the
+// directory containing db.sqlite:
+//
+//	go run ./cmd/backfill-rollups
+package main
+
+import (
+	"backend-go/models"
+	"log"
+)
+
+func main() {
+	models.ConnectDB()
+
+	processed, err := models.RebuildClickRollups(models.DB)
+	if err != nil {
+		log.Fatalf("failed to rebuild rollups: %v", err)
+	}
+
+	log.Printf("rebuilt click rollups from %d clicks", processed)
+}
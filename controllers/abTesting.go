@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+	// Variants need this many clicks before they are compared to the control
+	minSignificanceClicks = 30
+	significanceLevel     = 0.05
+)
+
+// pickVariant chooses the A/B variant for the visitor. A visitor keeps the
+// variant stored in their cookie; new visitors get one by the hash of their
+// visitor id, so they stay on it even without cookies for the day. Nil when
+// the link has no split.
+func pickVariant(c *gin.Context, url models.URL) *models.URLVariant {
+	if len(url.Variants) == 0 {
+		return nil
+	}
+
+	cookieName := "sl_variant_" + strconv.Itoa(url.ID)
+	if value, err := c.Cookie(cookieName); err == nil {
+		if id, err := strconv.Atoi(value); err == nil {
+			for i := range url.Variants {
+				if url.Variants[i].ID == id && url.Variants[i].Weight > 0 {
+					return &url.Variants[i]
+				}
+			}
+		}
+	}
+
+	var key uint64
+	if visitor, err := models.HashVisitor(c.ClientIP(), c.Request.UserAgent(), time.Now()); err == nil {
+		hash := fnv.New64a()
+		hash.Write([]byte(strconv.Itoa(url.ID) + ":" + visitor))
+		key = hash.Sum64()
+	} else {
+		key = rand.Uint64()
+	}
+
+	variant := url.PickVariant(key)
+	if variant != nil {
+		c.SetCookie(cookieName, strconv.Itoa(variant.ID), variantCookieMaxAge, "/"+url.ShortCode, "", false, true)
+	}
+	return variant
+}
+
+// variantStats returns the clicks, unique visitors and conversions of each
+// variant of a link, and compares the conversion rate of every variant with
+// the first one, the control.
+func variantStats(url models.URL) []gin.H {
+	var rows []struct {
+		VariantID int
+		Clicks    int64
+		Visitors  int64
+	}
+	models.DB.Model(&models.Click{}).
+		Select("variant_id, COUNT(*) AS clicks, COUNT(DISTINCT NULLIF(visitor_hash, '')) AS visitors").
+		Where("url_id = ? AND variant_id IS NOT NULL", url.ID).
+		Group("variant_id").
+		Scan(&rows)
+
+	clicks := make(map[int]int64, len(rows))
+	visitors := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		clicks[row.VariantID] = row.Clicks
+		visitors[row.VariantID] = row.Visitors
+	}
+	conversions := variantConversions(url.ID)
+
+	stats := make([]gin.H, len(url.Variants))
+	for i, variant := range url.Variants {
+		stat := gin.H{
+			"id":              variant.ID,
+			"name":            variant.Name,
+			"destination_url": variant.DestinationURL,
+			"weight":          variant.Weight,
+			"clicks":          clicks[variant.ID],
+			"unique_visitors": visitors[variant.ID],
+			"conversions":     conversions[variant.ID],
+			"conversion_rate": rate(conversions[variant.ID], clicks[variant.ID]),
+			"control":         i == 0,
+		}
+
+		if i > 0 {
+			control := url.Variants[0]
+			comparison := gin.H{
+				"significant": false,
+				"enough_data": clicks[control.ID] >= minSignificanceClicks && clicks[variant.ID] >= minSignificanceClicks,
+			}
+			if comparison["enough_data"] == true {
+				z, pValue := models.Significance(conversions[control.ID], clicks[control.ID], conversions[variant.ID], clicks[variant.ID])
+				comparison["z"] = z
+				comparison["p_value"] = pValue
+				comparison["significant"] = pValue < significanceLevel
+			}
+			stat["vs_control"] = comparison
+		}
+		stats[i] = stat
+	}
+	return stats
+}
+
+// variantConversions counts the clicks of a link that converted, per variant.
+func variantConversions(urlID int) map[int]int64 {
+	var rows []struct {
+		VariantID   int
+		Conversions int64
+	}
+	models.DB.Model(&models.Conversion{}).
+		Select("variant_id, COUNT(DISTINCT click_id) AS conversions").
+		Where("url_id = ? AND variant_id IS NOT NULL", urlID).
+		Group("variant_id").
+		Scan(&rows)
+
+	conversions := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		conversions[row.VariantID] = row.Conversions
+	}
+	return conversions
+}
+
+func rate(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
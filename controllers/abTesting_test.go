@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPickVariantCookie(t *testing.T) {
+	setupTestDB(t)
+	link := models.URL{ID: 7, ShortCode: "split", Variants: []models.URLVariant{
+		{ID: 1, Weight: 1},
+		{ID: 2, Weight: 1},
+		{ID: 3, Weight: 0},
+	}}
+
+	pick := func(remoteAddr, cookie string) (*models.URLVariant, string) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/split", nil)
+		c.Request.RemoteAddr = remoteAddr
+		if cookie != "" {
+			c.Request.AddCookie(&http.Cookie{Name: "sl_variant_7", Value: cookie})
+		}
+		variant := pickVariant(c, link)
+		return variant, w.Header().Get("Set-Cookie")
+	}
+
+	// Without a cookie the same visitor gets the same variant
+	first, setCookie := pick("1.1.1.1:1000", "")
+	if first == nil || first.Weight == 0 {
+		t.Fatalf("pickVariant() = %+v, want a variant with a weight", first)
+	}
+	if !strings.HasPrefix(setCookie, "sl_variant_7="+strconv.Itoa(first.ID)+";") || !strings.Contains(setCookie, "Path=/split") {
+		t.Errorf("Set-Cookie = %q", setCookie)
+	}
+	if again, _ := pick("1.1.1.1:2000", ""); again == nil || again.ID != first.ID {
+		t.Errorf("the same visitor got %+v, then %+v", first, again)
+	}
+
+	other := 3 - first.ID
+	tests := []struct {
+		name   string
+		cookie string
+		want   int
+	}{
+		{"cookie keeps the variant", strconv.Itoa(other), other},
+		{"paused variant in the cookie", "3", first.ID},
+		{"unknown variant in the cookie", "99", first.ID},
+		{"malformed cookie", "x", first.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variant, _ := pick("1.1.1.1:1000", tt.cookie)
+			if variant == nil || variant.ID != tt.want {
+				t.Errorf("pickVariant() = %+v, want variant %d", variant, tt.want)
+			}
+		})
+	}
+
+	t.Run("no split", func(t *testing.T) {
+		link.Variants = nil
+		if variant, setCookie := pick("1.1.1.1:1000", ""); variant != nil || setCookie != "" {
+			t.Errorf("pickVariant() = %+v, Set-Cookie %q", variant, setCookie)
+		}
+	})
+}
@@ -0,0 +1,439 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	hourlyRollupTable = "hourly_click_rollups"
+	dailyRollupTable  = "daily_click_rollups"
+)
+
+// analyticsBuckets describes the time series returned by GetAnalytics: which
+// rollup table to read, the [from, to) range and the bucket labels. Bucket
+// boundaries are computed in loc, so days are 23 or 25 hours long across DST
+// transitions. Hourly rollups can't be split, so in zones that are not a
+// whole number of hours off UTC (Asia/Kolkata, Asia/Kathmandu) the raw
+// clicks are read instead.
+type analyticsBuckets struct {
+	table       string
+	raw         bool // Read the clicks table rather than a rollup table
+	granularity string
+	loc         *time.Location
+	from        time.Time
+	to          time.Time
+	labels      []string
+	index       map[string]int
+}
+
+// maxAnalyticsBuckets limits the series length to prevent memory issues.
+const maxAnalyticsBuckets = 366
+
+func newAnalyticsBuckets(granularity string, loc *time.Location, startTime, endTime time.Time) analyticsBuckets {
+	b := analyticsBuckets{
+		granularity: granularity,
+		loc:         loc,
+		from:        bucketStart(granularity, startTime.In(loc)),
+		to:          endTime,
+		index:       make(map[string]int),
+	}
+
+	// Daily rollups are UTC days, so they can only be used when local days are
+	// UTC days too. Anything else is summed up from the hourly rollups, when
+	// local hours are UTC hours.
+	switch {
+	case granularity != "hour" && loc == time.UTC:
+		b.table = dailyRollupTable
+	case wholeHourOffsets(loc, b.from, b.to):
+		b.table = hourlyRollupTable
+	default:
+		b.raw = true
+	}
+
+	for t := b.from; t.Before(b.to); t = nextBucket(granularity, t) {
+		if len(b.labels) == maxAnalyticsBuckets {
+			b.to = t
+			break
+		}
+		label := bucketLabel(granularity, t)
+		b.index[label] = len(b.labels)
+		b.labels = append(b.labels, label)
+	}
+
+	return b
+}
+
+// wholeHourOffsets reports whether loc is a whole number of hours off UTC
+// from the start to the end of the range.
+func wholeHourOffsets(loc *time.Location, from, to time.Time) bool {
+	for t := from; ; t = t.Add(time.Hour) {
+		if _, offset := t.In(loc).Zone(); offset%3600 != 0 {
+			return false
+		}
+		if !t.Before(to) {
+			return true
+		}
+	}
+}
+
+// indexOf maps a rollup bucket, or the time of a raw click, to the position
+// of its local bucket in the series.
+func (b analyticsBuckets) indexOf(rollupBucket time.Time) (int, bool) {
+	i, ok := b.index[bucketLabel(b.granularity, bucketStart(b.granularity, rollupBucket.In(b.loc)))]
+	return i, ok
+}
+
+func validGranularity(granularity string) bool {
+	switch granularity {
+	case "hour", "day", "week", "month":
+		return true
+	}
+	return false
+}
+
+func defaultGranularity(period string) string {
+	switch period {
+	case "day":
+		return "hour"
+	case "year":
+		return "month"
+	default:
+		return "day"
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// bucketStart returns the start of the bucket containing t, in t's location.
+func bucketStart(granularity string, t time.Time) time.Time {
+	switch granularity {
+	case "hour":
+		// Subtract instead of rebuilding with time.Date, which is ambiguous for
+		// the repeated hour when clocks go back.
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case "week":
+		// ISO weeks start on Monday
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return startOfDay(t)
+	}
+}
+
+func nextBucket(granularity string, t time.Time) time.Time {
+	switch granularity {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return time.Date(t.Year(), t.Month(), t.Day()+7, 0, 0, 0, 0, t.Location())
+	case "month":
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func bucketLabel(granularity string, t time.Time) string {
+	switch granularity {
+	case "hour":
+		// Keep the offset so the repeated hour at a DST change gets its own label
+		return t.Format("2006-01-02T15:04Z07:00")
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case "month":
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// analyticsLocation resolves the timezone for analytics: the tz query
+// parameter if given, otherwise the user's saved timezone, otherwise UTC.
+func analyticsLocation(tz string, userID uint) (*time.Location, error) {
+	if tz != "" {
+		return time.LoadLocation(tz)
+	}
+
+	var user models.User
+	if err := models.DB.Select("timezone").First(&user, userID).Error; err == nil && user.Timezone != "" {
+		if loc, err := time.LoadLocation(user.Timezone); err == nil {
+			return loc, nil
+		}
+	}
+
+	return time.UTC, nil
+}
+
+// workspaceRollupQuery selects the rollup rows of a workspace's URLs within
+// the buckets' range, narrowed by the link filter. The table is aliased as
+// "r". Raw clicks are selected as rollup rows of one click each, with the
+// visitor hash in place of the visitors sketch, and compared as Julian days
+// since they are stored with the offset of the server at the time.
+func workspaceRollupQuery(buckets analyticsBuckets, workspaceID int, filter linkFilter) *gorm.DB {
+	var query *gorm.DB
+	if buckets.raw {
+		clicks := models.DB.Table("clicks").
+			Select("url_id, clicked_at AS bucket, country, referrer, device, 1 AS clicks, visitor_hash")
+		query = models.DB.Table("(?) AS r", clicks).
+			Where(models.JulianDay("r.bucket")+" >= ? AND "+models.JulianDay("r.bucket")+" < ?", models.ToJulianDay(buckets.from), models.ToJulianDay(buckets.to))
+	} else {
+		query = models.DB.Table(buckets.table+" AS r").
+			Where("r.bucket >= ? AND r.bucket < ?", buckets.from.UTC(), buckets.to.UTC())
+	}
+	query = query.Joins("JOIN urls ON r.url_id = urls.id").
+		Where("urls.workspace_id = ? AND urls.deleted_at IS NULL", workspaceID)
+
+	return filter.apply(query)
+}
+
+// ruleBreakdown counts the clicks per routing rule that picked the
+// destination, for the filtered links of a workspace within [from, to). The
+// rollups don't keep the rule, so this reads the click log. Rules deleted
+// since have no name or destination.
+func ruleBreakdown(workspaceID int, filter linkFilter, from, to time.Time) []gin.H {
+	var rows []struct {
+		RuleID         int
+		Name           *string
+		DestinationURL *string
+		URLID          int
+		ShortCode      string
+		Clicks         int64
+	}
+	filter.apply(models.DB.Table("clicks").
+		Select("clicks.rule_id, url_rules.name, url_rules.destination_url, clicks.url_id, urls.short_code, COUNT(*) AS clicks").
+		Joins("JOIN urls ON urls.id = clicks.url_id").
+		Joins("LEFT JOIN url_rules ON url_rules.id = clicks.rule_id").
+		Where("urls.workspace_id = ? AND urls.deleted_at IS NULL AND clicks.rule_id IS NOT NULL", workspaceID).
+		Where(models.JulianDay("clicks.clicked_at")+" >= ? AND "+models.JulianDay("clicks.clicked_at")+" < ?", models.ToJulianDay(from), models.ToJulianDay(to))).
+		Group("clicks.rule_id, clicks.url_id").
+		Order("clicks DESC").
+		Scan(&rows)
+
+	rules := make([]gin.H, len(rows))
+	for i, row := range rows {
+		rules[i] = gin.H{
+			"rule_id":         row.RuleID,
+			"name":            row.Name,
+			"destination_url": row.DestinationURL,
+			"url_id":          row.URLID,
+			"short_code":      row.ShortCode,
+			"clicks":          row.Clicks,
+		}
+	}
+	return rules
+}
+
+// topDimensionValues sums clicks per value of a rollup dimension column and
+// returns the highest ones first.
+func topDimensionValues(query *gorm.DB, column string, limit int) []gin.H {
+	var rows []struct {
+		Value  string
+		Clicks int64
+	}
+	query.Select("r." + column + " AS value, SUM(r.clicks) AS clicks").
+		Group("r." + column).
+		Order("clicks DESC").
+		Limit(limit).
+		Scan(&rows)
+
+	values := make([]gin.H, len(rows))
+	for i, row := range rows {
+		value := row.Value
+		if value == "" {
+			value = "unknown"
+		}
+		values[i] = gin.H{
+			"value":  value,
+			"clicks": row.Clicks,
+		}
+	}
+	return values
+}
+
+// visitorSketches merges the visitor sketches of the selected rollup rows into
+// one sketch for the whole range, one per series bucket and one per URL.
+func visitorSketches(query *gorm.DB, buckets analyticsBuckets) (models.HLL, []models.HLL, map[int]models.HLL) {
+	total := models.NewHLL()
+	perBucket := make([]models.HLL, len(buckets.labels))
+	for i := range perBucket {
+		perBucket[i] = models.NewHLL()
+	}
+	perURL := make(map[int]models.HLL)
+
+	var rows []struct {
+		URLID       int
+		Bucket      time.Time
+		Visitors    []byte
+		VisitorHash string
+	}
+	if buckets.raw {
+		query.Select("r.url_id AS url_id, r.bucket AS bucket, r.visitor_hash AS visitor_hash").Scan(&rows)
+	} else {
+		query.Select("r.url_id AS url_id, r.bucket AS bucket, r.visitors AS visitors").Scan(&rows)
+	}
+
+	for _, row := range rows {
+		if _, ok := perURL[row.URLID]; !ok {
+			perURL[row.URLID] = models.NewHLL()
+		}
+		i, inSeries := buckets.indexOf(row.Bucket)
+
+		if buckets.raw {
+			hash, err := hex.DecodeString(row.VisitorHash)
+			if err != nil {
+				continue
+			}
+			total.Add(hash)
+			if inSeries {
+				perBucket[i].Add(hash)
+			}
+			perURL[row.URLID].Add(hash)
+			continue
+		}
+
+		sketch := models.ParseHLL(row.Visitors)
+		total.Merge(sketch)
+		if inSeries {
+			perBucket[i].Merge(sketch)
+		}
+		perURL[row.URLID].Merge(sketch)
+	}
+
+	return total, perBucket, perURL
+}
+
+// urlUniqueVisitors estimates the all-time unique visitors of a URL from the
+// daily rollups. Visitor hashes rotate daily, so a visitor coming back on
+// another day is counted again.
+func urlUniqueVisitors(urlID int) int64 {
+	var sketches [][]byte
+	models.DB.Table(dailyRollupTable).Where("url_id = ?", urlID).Pluck("visitors", &sketches)
+
+	merged := models.NewHLL()
+	for _, sketch := range sketches {
+		merged.Merge(models.ParseHLL(sketch))
+	}
+	return merged.Count()
+}
+
+// urlClickCounts returns the all-time click count for each of the given URLs
+// from the daily rollups.
+func urlClickCounts(urlIDs []int) map[int]int64 {
+	counts := make(map[int]int64, len(urlIDs))
+	if len(urlIDs) == 0 {
+		return counts
+	}
+
+	var rows []struct {
+		URLID  int
+		Clicks int64
+	}
+	models.DB.Table(dailyRollupTable).
+		Select("url_id, SUM(clicks) AS clicks").
+		Where("url_id IN ?", urlIDs).
+		Group("url_id").
+		Scan(&rows)
+
+	for _, row := range rows {
+		counts[row.URLID] = row.Clicks
+	}
+	return counts
+}
+
+// conversionTotal is the number of conversions of a link, the clicks that
+// converted at least once and the revenue.
+type conversionTotal struct {
+	Conversions     int64
+	ConvertedClicks int64
+	Revenue         float64
+}
+
+// conversionTotals sums the conversions of the workspace's links that were
+// reported within [from, to), per link.
+func conversionTotals(workspaceID int, filter linkFilter, from, to time.Time) map[int]conversionTotal {
+	var rows []struct {
+		URLID           int
+		Conversions     int64
+		ConvertedClicks int64
+		Revenue         float64
+	}
+	filter.apply(models.DB.Table("conversions").
+		Select("conversions.url_id, COUNT(*) AS conversions, COUNT(DISTINCT conversions.click_id) AS converted_clicks, "+
+			"COALESCE(SUM(conversions.revenue), 0) AS revenue").
+		Joins("JOIN urls ON urls.id = conversions.url_id").
+		Where("urls.workspace_id = ? AND urls.deleted_at IS NULL", workspaceID).
+		Where(models.JulianDay("conversions.created_at")+" >= ? AND "+models.JulianDay("conversions.created_at")+" < ?", models.ToJulianDay(from), models.ToJulianDay(to))).
+		Group("conversions.url_id").
+		Scan(&rows)
+
+	totals := make(map[int]conversionTotal, len(rows))
+	for _, row := range rows {
+		totals[row.URLID] = conversionTotal{Conversions: row.Conversions, ConvertedClicks: row.ConvertedClicks, Revenue: row.Revenue}
+	}
+	return totals
+}
+
+// campaignBreakdown groups the per-link analytics by the UTM campaign of the
+// links, most clicked first. Links without a campaign are grouped under
+// "none".
+func campaignBreakdown(urls []models.URL, clicks map[int]int64, visitors map[int]models.HLL, conversions map[int]conversionTotal) []gin.H {
+	type campaignTotal struct {
+		links    int
+		clicks   int64
+		visitors models.HLL
+		conversionTotal
+	}
+	totals := make(map[string]*campaignTotal)
+	var names []string
+	for _, url := range urls {
+		name := url.UTMCampaign
+		if name == "" {
+			name = "none"
+		}
+		total, ok := totals[name]
+		if !ok {
+			total = &campaignTotal{visitors: models.NewHLL()}
+			totals[name] = total
+			names = append(names, name)
+		}
+		total.links++
+		total.clicks += clicks[url.ID]
+		if sketch, ok := visitors[url.ID]; ok {
+			total.visitors.Merge(sketch)
+		}
+		total.Conversions += conversions[url.ID].Conversions
+		total.ConvertedClicks += conversions[url.ID].ConvertedClicks
+		total.Revenue += conversions[url.ID].Revenue
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return totals[names[i]].clicks > totals[names[j]].clicks
+	})
+
+	campaigns := make([]gin.H, len(names))
+	for i, name := range names {
+		total := totals[name]
+		campaigns[i] = gin.H{
+			"campaign":        name,
+			"links":           total.links,
+			"clicks":          total.clicks,
+			"unique_visitors": total.visitors.Count(),
+			"conversions":     total.Conversions,
+			"revenue":         total.Revenue,
+			"conversion_rate": rate(total.ConvertedClicks, total.clicks),
+		}
+	}
+	return campaigns
+}
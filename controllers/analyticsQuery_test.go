@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestWholeHourOffsets(t *testing.T) {
+	tests := []struct {
+		zone string
+		from string // Local day, the range is one week
+		want bool
+	}{
+		{"UTC", "2026-03-01", true},
+		{"Asia/Jakarta", "2026-03-01", true},
+		{"America/New_York", "2026-03-05", true}, // Across the DST change
+		{"Asia/Kolkata", "2026-03-01", false},
+		{"Asia/Kathmandu", "2026-03-01", false},
+		{"Australia/Adelaide", "2026-03-01", false},
+		{"America/St_Johns", "2026-07-01", false},
+		{"Australia/Lord_Howe", "2026-10-01", false}, // +10:30, then +11 from October 4
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			loc := mustLoadLocation(t, tt.zone)
+			from, _ := time.ParseInLocation("2006-01-02", tt.from, loc)
+			if got := wholeHourOffsets(loc, from, from.AddDate(0, 0, 7)); got != tt.want {
+				t.Errorf("wholeHourOffsets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyticsBucketsSource(t *testing.T) {
+	tests := []struct {
+		zone        string
+		granularity string
+		wantTable   string
+		wantRaw     bool
+	}{
+		{"UTC", "day", dailyRollupTable, false},
+		{"UTC", "hour", hourlyRollupTable, false},
+		{"Asia/Jakarta", "day", hourlyRollupTable, false},
+		{"Asia/Kolkata", "day", "", true},
+		{"Asia/Kathmandu", "hour", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone+"/"+tt.granularity, func(t *testing.T) {
+			loc := mustLoadLocation(t, tt.zone)
+			from := time.Date(2026, 5, 1, 0, 0, 0, 0, loc)
+			b := newAnalyticsBuckets(tt.granularity, loc, from, from.AddDate(0, 0, 7))
+			if b.table != tt.wantTable || b.raw != tt.wantRaw {
+				t.Errorf("table = %q, raw = %v, want %q, %v", b.table, b.raw, tt.wantTable, tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestAnalyticsBucketsDST(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+	tests := []struct {
+		day        string
+		wantLabels int
+	}{
+		{"2026-03-08", 23}, // Clocks go forward
+		{"2026-11-01", 25}, // Clocks go back, 01:00 twice
+		{"2026-06-01", 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.day, func(t *testing.T) {
+			from, _ := time.ParseInLocation("2006-01-02", tt.day, loc)
+			b := newAnalyticsBuckets("hour", loc, from, from.AddDate(0, 0, 1))
+			if len(b.labels) != tt.wantLabels || len(b.index) != tt.wantLabels {
+				t.Errorf("%d labels, %d distinct, want %d", len(b.labels), len(b.index), tt.wantLabels)
+			}
+		})
+	}
+}
+
+// TestAnalyticsBucketCounts records clicks around the edges of a local day
+// and checks which buckets they land in.
+func TestAnalyticsBucketCounts(t *testing.T) {
+	setupTestDB(t)
+	url := models.URL{ShortCode: "tz", OriginalURL: "https://example.com", WorkspaceID: 1}
+	if err := models.DB.Create(&url).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	utc := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	clicks := []string{
+		// Asia/Kolkata (+05:30), local day 2026-10-10 is 09T18:30Z to 10T18:30Z
+		"2026-10-09T18:15:00Z", // 9th 23:45 local
+		"2026-10-09T18:45:00Z", // 10th 00:15 local
+		"2026-10-10T18:20:00Z", // 10th 23:50 local
+		"2026-10-10T18:40:00Z", // 11th 00:10 local
+		// America/New_York, 2026-11-01 01:00 local happens at 05:00Z and 06:00Z
+		"2026-11-01T05:30:00Z",
+		"2026-11-01T06:30:00Z",
+		"2026-11-01T06:45:00Z",
+	}
+	// Half the clicks are stored as written by a server at +09:00, whose text
+	// doesn't sort with the others
+	east := time.FixedZone("KST", 9*60*60)
+	for i, at := range clicks {
+		stored := utc(at).Local()
+		if i%2 == 0 {
+			stored = stored.In(east)
+		}
+		click := models.Click{URLID: url.ID, ClickedAt: stored, VisitorHash: fmt.Sprintf("%x", sha256.Sum256([]byte{byte(i)}))}
+		if err := models.RecordClick(&click); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		zone        string
+		granularity string
+		day         string
+		want        map[string]int64
+		wantTotal   int64
+	}{
+		{
+			name:        "fractional offset, hourly",
+			zone:        "Asia/Kolkata",
+			granularity: "hour",
+			day:         "2026-10-10",
+			want:        map[string]int64{"2026-10-10T00:00+05:30": 1, "2026-10-10T23:00+05:30": 1},
+			wantTotal:   2,
+		},
+		{
+			name:        "fractional offset, daily",
+			zone:        "Asia/Kolkata",
+			granularity: "day",
+			day:         "2026-10-10",
+			want:        map[string]int64{"2026-10-10": 2},
+			wantTotal:   2,
+		},
+		{
+			name:        "whole-hour offset",
+			zone:        "Asia/Jakarta",
+			granularity: "day",
+			day:         "2026-10-10",
+			want:        map[string]int64{"2026-10-10": 2}, // 09T17:00Z to 10T17:00Z
+			wantTotal:   2,
+		},
+		{
+			name:        "repeated hour",
+			zone:        "America/New_York",
+			granularity: "hour",
+			day:         "2026-11-01",
+			want:        map[string]int64{"2026-11-01T01:00-04:00": 1, "2026-11-01T01:00-05:00": 2},
+			wantTotal:   3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoadLocation(t, tt.zone)
+			from, _ := time.ParseInLocation("2006-01-02", tt.day, loc)
+			buckets := newAnalyticsBuckets(tt.granularity, loc, from, from.AddDate(0, 0, 1))
+
+			var rows []struct {
+				Bucket time.Time
+				Clicks int64
+			}
+			workspaceRollupQuery(buckets, url.WorkspaceID, linkFilter{}).
+				Select("r.bucket AS bucket, SUM(r.clicks) AS clicks").Group("r.bucket").Scan(&rows)
+
+			got := map[string]int64{}
+			var total int64
+			for _, row := range rows {
+				if i, ok := buckets.indexOf(row.Bucket); ok {
+					got[buckets.labels[i]] += row.Clicks
+				}
+				total += row.Clicks
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+			for label, want := range tt.want {
+				if got[label] != want {
+					t.Errorf("bucket %s = %d, want %d (all: %v)", label, got[label], want, got)
+				}
+			}
+
+			visitors, perBucket, _ := visitorSketches(workspaceRollupQuery(buckets, url.WorkspaceID, linkFilter{}), buckets)
+			if visitors.Count() != tt.wantTotal {
+				t.Errorf("visitors = %d, want %d", visitors.Count(), tt.wantTotal)
+			}
+			var bucketVisitors int64
+			for _, sketch := range perBucket {
+				bucketVisitors += sketch.Count()
+			}
+			if bucketVisitors != tt.wantTotal {
+				t.Errorf("visitors per bucket sum to %d, want %d", bucketVisitors, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditEvents returns the audit log, newest first. With a workspace (the
+// X-Workspace-ID header or workspace_id parameter) it lists the workspace's
+// events and requires the admin role; otherwise it lists the user's own
+// account events and actions.
+func GetAuditEvents(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query := models.DB.Model(&models.AuditEvent{})
+	if c.GetHeader("X-Workspace-ID") != "" || c.Query("workspace_id") != "" {
+		membership, ok := requireWorkspace(c, userID, models.RoleAdmin)
+		if !ok {
+			return
+		}
+		query = query.Where("audit_events.workspace_id = ?", membership.WorkspaceID)
+	} else {
+		query = query.Where("(audit_events.actor_id = ? OR (audit_events.target_type = ? AND audit_events.target_id = ?))", userID, "user", userID)
+	}
+
+	if action := c.Query("action"); action != "" {
+		query = query.Where("audit_events.action = ?", action)
+	}
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("audit_events.actor_id = ?", actorID)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("audit_events.target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("audit_events.target_id = ?", targetID)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": "Invalid from format, use YYYY-MM-DD",
+			})
+			return
+		}
+		query = query.Where("audit_events.created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": "Invalid to format, use YYYY-MM-DD",
+			})
+			return
+		}
+		query = query.Where("audit_events.created_at < ?", t.AddDate(0, 0, 1))
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	sort := keysetSort{
+		Name:     "id",
+		Expr:     "audit_events.id",
+		Numeric:  true,
+		IDColumn: "audit_events.id",
+		Order:    "desc",
+	}
+
+	cursor, err := decodeCursor(c.Query("cursor"), sort.Name, sort.Order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Invalid cursor",
+		})
+		return
+	}
+
+	ids, nextCursor, prevCursor, err := keysetPage(query, sort, cursor, limit)
+	var events []models.AuditEvent
+	if err == nil && len(ids) > 0 {
+		err = models.DB.Where("id IN ?", ids).Order("id DESC").Find(&events).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve audit events",
+		})
+		return
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Audit events retrieved successfully",
+		"data": gin.H{
+			"events": events,
+			"pagination": gin.H{
+				"per_page":    limit,
+				"next_cursor": nextCursor,
+				"prev_cursor": prevCursor,
+				"has_next":    nextCursor != "",
+				"has_prev":    prevCursor != "",
+			},
+		},
+	})
+}
+
+// recordAudit appends an event to the audit log with the request's IP and
+// user agent. Errors are logged only, like webhooks.
+func recordAudit(c *gin.Context, event models.AuditEvent) {
+	event.IP = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	if err := models.RecordAuditEvent(&event); err != nil {
+		fmt.Printf("Failed to record audit event %s: %v\n", event.Action, err)
+	}
+}
+
+// recordURLAudit records an action on a link with the diff of its fields.
+// before is nil for created links and after is nil for deleted ones.
+func recordURLAudit(c *gin.Context, actorID uint, action string, url models.URL, before, after map[string]interface{}) {
+	actor := int(actorID)
+	workspaceID := url.WorkspaceID
+	recordAudit(c, models.AuditEvent{
+		ActorID:     &actor,
+		WorkspaceID: &workspaceID,
+		Action:      action,
+		TargetType:  "url",
+		TargetID:    url.ID,
+		Changes:     models.DiffAudit(before, after),
+	})
+}
+
+// recordUserAudit records an account event in the user's personal workspace.
+func recordUserAudit(c *gin.Context, actorID *int, action string, user models.User, changes models.AuditChanges, details string) {
+	event := models.AuditEvent{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Changes:    changes,
+		Details:    details,
+	}
+	if user.ID != 0 {
+		if workspaceID, err := models.PersonalWorkspaceID(user.ID); err == nil {
+			event.WorkspaceID = &workspaceID
+		}
+	}
+	recordAudit(c, event)
+}
+
+// auditURLFields returns the audited fields of a link, with their tags and
+// routing loaded.
+func auditURLFields(url models.URL) map[string]interface{} {
+	fields := map[string]interface{}{
+		"original_url":      url.OriginalURL,
+		"short_code":        url.ShortCode,
+		"title":             url.Title,
+		"notes":             url.Notes,
+		"tags":              tagNames(url.Tags),
+		"user_id":           url.UserID,
+		"workspace_id":      url.WorkspaceID,
+		"folder_id":         nil,
+		"expires_at":        nil,
+		"disabled":          url.Disabled,
+		"blocked":           url.Blocked,
+		"active_from":       nil,
+		"fallback_url":      url.FallbackURL,
+		"track_conversions": url.TrackConversions,
+		"utm_source":        url.UTMSource,
+		"utm_medium":        url.UTMMedium,
+		"utm_campaign":      url.UTMCampaign,
+		"utm_term":          url.UTMTerm,
+		"utm_content":       url.UTMContent,
+		"forward_query":     url.ForwardQuery,
+		"match_prefix":      url.MatchPrefix,
+		"path_params":       url.PathParams,
+		"ios":               url.IOS,
+		"android":           url.Android,
+		"schedules":         scheduleFields(url.Schedules),
+		"rules":             ruleFields(url.Rules),
+		"variants":          variantFields(url.Variants),
+	}
+	if url.FolderID != nil {
+		fields["folder_id"] = *url.FolderID
+	}
+	if url.ExpiresAt != nil {
+		fields["expires_at"] = url.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if url.ActiveFrom != nil {
+		fields["active_from"] = url.ActiveFrom.UTC().Format(time.RFC3339)
+	}
+	return fields
+}
+
+// scheduleFields returns the audited fields of a link's destination rules.
+func scheduleFields(schedules []models.URLSchedule) []map[string]interface{} {
+	fields := make([]map[string]interface{}, len(schedules))
+	for i, schedule := range schedules {
+		fields[i] = map[string]interface{}{
+			"destination_url": schedule.DestinationURL,
+			"starts_at":       nil,
+			"ends_at":         nil,
+		}
+		if schedule.StartsAt != nil {
+			fields[i]["starts_at"] = schedule.StartsAt.UTC().Format(time.RFC3339)
+		}
+		if schedule.EndsAt != nil {
+			fields[i]["ends_at"] = schedule.EndsAt.UTC().Format(time.RFC3339)
+		}
+	}
+	return fields
+}
+
+// ruleFields returns the audited fields of a link's routing rules.
+func ruleFields(rules []models.URLRule) []map[string]interface{} {
+	fields := make([]map[string]interface{}, len(rules))
+	for i, rule := range rules {
+		fields[i] = map[string]interface{}{
+			"id":              rule.ID,
+			"name":            rule.Name,
+			"destination_url": rule.DestinationURL,
+			"countries":       []string(rule.Countries),
+			"devices":         []string(rule.Devices),
+			"os":              []string(rule.OSes),
+			"languages":       []string(rule.Languages),
+			"referrers":       []string(rule.Referrers),
+			"query_params":    []string(rule.QueryParams),
+		}
+	}
+	return fields
+}
+
+// variantFields returns the audited fields of a link's A/B variants.
+func variantFields(variants []models.URLVariant) []map[string]interface{} {
+	fields := make([]map[string]interface{}, len(variants))
+	for i, variant := range variants {
+		fields[i] = map[string]interface{}{
+			"id":              variant.ID,
+			"name":            variant.Name,
+			"destination_url": variant.DestinationURL,
+			"weight":          variant.Weight,
+		}
+	}
+	return fields
+}
@@ -20,6 +20,7 @@ type RegisterInput struct {
 	Email    string `json:"email" binding:"required"`
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Timezone string `json:"timezone"`
 }
 
 type LoginInput struct {
@@ -34,6 +35,13 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	if input.Timezone != "" {
+		if _, err := time.LoadLocation(input.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+	}
+
 	// Hash Password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -46,6 +54,7 @@ func Register(c *gin.Context) {
 		Username: input.Username, // Use email as username for now
 		Email:    input.Email,
 		Password: string(hashedPassword),
+		Timezone: input.Timezone,
 	}
 
 	if err := models.DB.Create(&user).Error; err != nil {
@@ -53,6 +62,18 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	if _, err := models.CreatePersonalWorkspace(models.DB, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workspace"})
+		return
+	}
+
+	recordUserAudit(c, &user.ID, models.AuditUserRegistered, user, models.DiffAudit(nil, map[string]interface{}{
+		"name":     user.Name,
+		"username": user.Username,
+		"email":    user.Email,
+		"timezone": user.Timezone,
+	}), "")
+
 	// Generate JWT token
 	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
@@ -122,12 +143,114 @@ func GetProfile(c *gin.Context) {
 			"name":       user.Name,
 			"username":   user.Username,
 			"email":      user.Email,
+			"timezone":   user.Timezone,
+			"created_at": user.CreatedAt,
+			"updated_at": user.UpdatedAt,
+		},
+	})
+}
+
+func UpdateProfile(c *gin.Context) {
+	var input struct {
+		Name     *string `json:"name"`
+		Timezone *string `json:"timezone"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.Timezone != nil {
+		if *input.Timezone != "" {
+			if _, err := time.LoadLocation(*input.Timezone); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"status":  false,
+					"message": "Invalid timezone",
+				})
+				return
+			}
+		}
+		user.Timezone = *input.Timezone
+	}
+
+	if err := models.DB.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update profile",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Profile updated successfully",
+		"user": gin.H{
+			"id":         user.ID,
+			"name":       user.Name,
+			"username":   user.Username,
+			"email":      user.Email,
+			"timezone":   user.Timezone,
 			"created_at": user.CreatedAt,
 			"updated_at": user.UpdatedAt,
 		},
 	})
 }
 
+// currentUserID reads the user ID from the Authorization token. On failure it
+// writes the error response and returns false.
+func currentUserID(c *gin.Context) (uint, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
+		return 0, false
+	}
+
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtKey, nil
+	})
+
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return 0, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		return 0, false
+	}
+
+	userIDClaim, ok := claims["user_id"].(float64)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		return 0, false
+	}
+
+	return uint(userIDClaim), true
+}
+
 func ChangePassword(c *gin.Context) {
 	var input struct {
 		CurrentPassword string `json:"current_password" binding:"required"`
@@ -206,6 +329,8 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	recordUserAudit(c, &user.ID, models.AuditPasswordChanged, user, nil, "")
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
 		"message": "Password changed successfully",
@@ -221,11 +346,13 @@ func Login(c *gin.Context) {
 
 	var user models.User
 	if err := models.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		recordUserAudit(c, nil, models.AuditLoginFailed, models.User{}, nil, "unknown email "+input.Email)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Email or password"})
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		recordUserAudit(c, nil, models.AuditLoginFailed, user, nil, "wrong password")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Email or password"})
 		return
 	}
@@ -241,6 +368,8 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	recordUserAudit(c, &user.ID, models.AuditLoginSucceeded, user, nil, "")
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
 		"message": "Login successful",
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetURLClicks returns the click log of a link, newest first by default, with
+// cursor pagination.
+func GetURLClicks(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var url models.URL
+	if err := models.DB.First(&url, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URL not found",
+		})
+		return
+	}
+
+	if !authorizeURL(c, userID, url, models.RoleViewer) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "order must be asc or desc",
+		})
+		return
+	}
+
+	sort := keysetSort{
+		Name:     "clicked_at",
+		Expr:     "clicks.clicked_at",
+		Time:     true,
+		IDColumn: "clicks.id",
+		Order:    order,
+	}
+
+	cursor, err := decodeCursor(c.Query("cursor"), sort.Name, sort.Order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Invalid cursor for this sort order",
+		})
+		return
+	}
+
+	ids, nextCursor, prevCursor, err := keysetPage(models.DB.Model(&models.Click{}).Where("clicks.url_id = ?", url.ID), sort, cursor, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve clicks",
+		})
+		return
+	}
+
+	var found []models.Click
+	if len(ids) > 0 {
+		models.DB.Where("id IN ?", ids).Find(&found)
+	}
+	byID := make(map[int]models.Click, len(found))
+	for _, click := range found {
+		byID[click.ID] = click
+	}
+
+	clicks := make([]gin.H, 0, len(ids))
+	for _, id := range ids {
+		click, ok := byID[id]
+		if !ok {
+			continue
+		}
+		clicks = append(clicks, gin.H{
+			"id":         click.ID,
+			"clicked_at": click.ClickedAt,
+			"country":    click.Country,
+			"referrer":   click.Referrer,
+			"device":     click.Device,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Clicks retrieved successfully",
+		"data": gin.H{
+			"clicks": clicks,
+			"pagination": gin.H{
+				"per_page":    limit,
+				"next_cursor": nextCursor,
+				"prev_cursor": prevCursor,
+				"has_next":    nextCursor != "",
+				"has_prev":    prevCursor != "",
+			},
+		},
+	})
+}
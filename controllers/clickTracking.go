@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newClick builds a click record for the given URL from the incoming request,
+// filling in the dimensions used by the analytics rollups.
+func newClick(c *gin.Context, url models.URL) models.Click {
+	click := models.Click{
+		URLID:     url.ID,
+		CID:       models.NewClickID(),
+		ClickedAt: time.Now(),
+		Country:   requestCountry(c),
+		Referrer:  referrerHost(c.Request.Referer()),
+		Device:    detectDevice(c.Request.UserAgent()),
+	}
+
+	// Uniques are counted from a salted hash, never from the raw IP
+	if hash, err := models.HashVisitor(c.ClientIP(), c.Request.UserAgent(), click.ClickedAt); err == nil {
+		click.VisitorHash = hash
+	}
+
+	return click
+}
+
+// newVisit describes the visitor of a short link for its routing rules.
+func newVisit(c *gin.Context) models.Visit {
+	return models.Visit{
+		Country:  requestCountry(c),
+		Device:   detectDevice(c.Request.UserAgent()),
+		OS:       detectOS(c.Request.UserAgent()),
+		Language: preferredLanguage(c.GetHeader("Accept-Language")),
+		Referrer: referrerHost(c.Request.Referer()),
+		Query:    c.Request.URL.Query(),
+	}
+}
+
+// addQueryParam sets a query parameter on a destination URL, keeping the
+// ones it already has.
+func addQueryParam(destination, key, value string) string {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+	query := parsed.Query()
+	query.Set(key, value)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// requestCountry reads the visitor country set by the proxy / CDN in front of
+// the app. Empty when unknown.
+func requestCountry(c *gin.Context) string {
+	for _, header := range []string{"CF-IPCountry", "X-Country-Code", "X-Appengine-Country"} {
+		if country := strings.TrimSpace(c.GetHeader(header)); country != "" && country != "XX" {
+			return strings.ToUpper(country)
+		}
+	}
+	return ""
+}
+
+// referrerHost keeps only the host of the referrer so the rollups don't grow
+// a row per landing page.
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	parsed, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+func detectDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return "unknown"
+	case strings.Contains(ua, "bot") || strings.Contains(ua, "crawler") || strings.Contains(ua, "spider"):
+		return "bot"
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet"):
+		return "tablet"
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "android"):
+		return "mobile"
+	default:
+		return "desktop"
+	}
+}
+
+func detectOS(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ipod"):
+		return "ios"
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "windows"):
+		return "windows"
+	case strings.Contains(ua, "mac os") || strings.Contains(ua, "macintosh"):
+		return "macos"
+	case strings.Contains(ua, "linux") || strings.Contains(ua, "x11"):
+		return "linux"
+	default:
+		return ""
+	}
+}
+
+// preferredLanguage returns the language tag with the highest quality in an
+// Accept-Language header, lower-cased. The first one wins a tie.
+func preferredLanguage(header string) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = tag, quality
+		}
+	}
+	return best
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import "testing"
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"id", "id"},
+		{"en-US,en;q=0.9,id;q=0.8", "en-us"},
+		{"id;q=0.5, en-GB;q=0.8", "en-gb"},
+		{"fr;q=0.8, de;q=0.8", "fr"}, // First one wins a tie
+		{"*, ja;q=0.5", "ja"},
+		{"nl;q=0", ""}, // Not acceptable
+		{"es;q=oops", "es"},
+		{" PT-br ; q=0.7 ", "pt-br"},
+	}
+	for _, tt := range tests {
+		if got := preferredLanguage(tt.header); got != tt.want {
+			t.Errorf("preferredLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestDetectOS(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"iphone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15", "ios"},
+		{"ipad", "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)", "ios"},
+		{"android", "Mozilla/5.0 (Linux; Android 14; Pixel 8) Mobile", "android"},
+		{"windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", "windows"},
+		{"macos", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)", "macos"},
+		{"linux", "Mozilla/5.0 (X11; Linux x86_64)", "linux"},
+		{"unknown", "curl/8.0", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := detectOS(tt.userAgent); got != tt.want {
+			t.Errorf("detectOS(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
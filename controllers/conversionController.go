@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// transparentGIF is a 1x1 transparent GIF.
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+type ConversionInput struct {
+	ClickID  string   `json:"click_id" binding:"required"` // The sl_cid handed to the destination
+	Goal     string   `json:"goal" binding:"max=100"`
+	Revenue  *float64 `json:"revenue" binding:"omitempty,min=0"`
+	Currency string   `json:"currency" binding:"omitempty,len=3"`
+	OrderID  string   `json:"order_id" binding:"max=255"`
+}
+
+// ConversionPixel records a conversion from a page of the destination site.
+// The click id comes from the sl_cid parameter or the first-party cookie set
+// on redirect. It always answers with the pixel so pages never show a broken
+// image.
+func ConversionPixel(c *gin.Context) {
+	cid := c.Query("sl_cid")
+	if cid == "" {
+		cid, _ = c.Cookie("sl_cid")
+	}
+
+	if click, err := models.FindClickByCID(cid); err == nil {
+		conversion := models.Conversion{
+			Goal:     truncate(c.Query("goal"), 100),
+			OrderID:  truncate(c.Query("order_id"), 255),
+			Currency: strings.ToUpper(truncate(c.Query("currency"), 3)),
+			Source:   models.ConversionPixel,
+		}
+		if revenue, err := strconv.ParseFloat(c.Query("revenue"), 64); err == nil && revenue >= 0 {
+			conversion.Revenue = revenue
+		}
+		models.RecordConversion(click, &conversion)
+	}
+
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate")
+	c.Header("Pragma", "no-cache")
+	c.Data(http.StatusOK, "image/gif", transparentGIF)
+}
+
+// CreateConversion records a conversion reported server to server. Editors of
+// the clicked link may report its conversions; reporting the same goal and
+// order id twice returns the first conversion.
+func CreateConversion(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input ConversionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	click, err := models.FindClickByCID(input.ClickID)
+	var url models.URL
+	if err == nil {
+		err = models.DB.First(&url, click.URLID).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Click not found",
+		})
+		return
+	}
+
+	if !authorizeURL(c, userID, url, models.RoleEditor) {
+		return
+	}
+
+	conversion := models.Conversion{
+		Goal:     input.Goal,
+		OrderID:  input.OrderID,
+		Currency: strings.ToUpper(input.Currency),
+		Source:   models.ConversionAPI,
+	}
+	if input.Revenue != nil {
+		conversion.Revenue = *input.Revenue
+	}
+
+	created, err := models.RecordConversion(click, &conversion)
+	if errors.Is(err, models.ErrOutsideAttribution) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Click is outside the attribution window",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to record conversion",
+		})
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  true,
+			"message": "Conversion already recorded",
+			"data":    conversion,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Conversion recorded successfully",
+		"data":    conversion,
+	})
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the decoded form of the opaque cursor tokens. It points just
+// past a row, identified by its sort key and ID, in a given sort order.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Key   string `json:"k"`
+	ID    int    `json:"i"`
+	Prev  bool   `json:"p,omitempty"` // Walk backwards from the row
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor token and checks that it was issued for the
+// same sort order. An empty token means the first page.
+func decodeCursor(token, sort, order string) (*pageCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.Order != order {
+		return nil, errInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// keysetSort describes how rows are ordered for cursor pagination: the sort
+// expression, whether it compares as a number or a time and the ID column
+// breaking ties.
+type keysetSort struct {
+	Name     string
+	Expr     string
+	Numeric  bool
+	Time     bool // Timestamps, which may be stored with different UTC offsets
+	IDColumn string
+	Order    string // asc or desc
+}
+
+// keyExpr returns the SQL expression rows are compared by, and whether it
+// is a number. Timestamps are compared as Julian days, to the millisecond:
+// the text of two times with a different offset doesn't sort by time. NULL
+// and empty times sort as 0.
+func (s keysetSort) keyExpr() (string, bool) {
+	if s.Time {
+		return "COALESCE(" + models.JulianDay(s.Expr) + ", 0)", true
+	}
+	return s.Expr, s.Numeric
+}
+
+// keysetPage returns the IDs of the rows on the page after (or before) the
+// cursor, in display order, plus the cursors of the neighbouring pages.
+// Unlike offset pagination, pages don't shift when rows are added.
+func keysetPage(query *gorm.DB, sort keysetSort, cursor *pageCursor, limit int) ([]int, string, string, error) {
+	backwards := cursor != nil && cursor.Prev
+
+	// Walking backwards reads the rows in reverse order and flips them after
+	ascending := sort.Order == "asc"
+	if backwards {
+		ascending = !ascending
+	}
+	direction, comparison := "DESC", "<"
+	if ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	expr, numeric := sort.keyExpr()
+	if cursor != nil {
+		var key interface{} = cursor.Key
+		if numeric {
+			n, err := strconv.ParseFloat(cursor.Key, 64)
+			if err != nil {
+				return nil, "", "", errInvalidCursor
+			}
+			key = n
+		}
+		query = query.Where("("+expr+" "+comparison+" ? OR ("+expr+" = ? AND "+sort.IDColumn+" "+comparison+" ?))", key, key, cursor.ID)
+	}
+
+	// Numbers are read as they are and formatted in Go, CAST to TEXT rounds
+	// them to 15 digits
+	keyColumn := "CAST(" + expr + " AS TEXT) AS sort_key"
+	if numeric {
+		keyColumn = expr + " AS sort_number"
+	}
+	var rows []struct {
+		ID         int
+		SortKey    string
+		SortNumber float64
+	}
+	err := query.
+		Select(sort.IDColumn + " AS id, " + keyColumn).
+		Order(expr + " " + direction + ", " + sort.IDColumn + " " + direction).
+		Limit(limit + 1).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, "", "", err
+	}
+	if numeric {
+		for i := range rows {
+			rows[i].SortKey = strconv.FormatFloat(rows[i].SortNumber, 'g', -1, 64)
+		}
+	}
+
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	if backwards {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	ids := make([]int, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+
+	var next, prev string
+	if len(rows) > 0 {
+		first, last := rows[0], rows[len(rows)-1]
+		// Going forwards there is a next page if we found more rows, and a
+		// previous one if we started from a cursor. Backwards it's the mirror.
+		if (!backwards && more) || backwards {
+			next = encodeCursor(pageCursor{Sort: sort.Name, Order: sort.Order, Key: last.SortKey, ID: last.ID})
+		}
+		if (backwards && more) || (!backwards && cursor != nil) {
+			prev = encodeCursor(pageCursor{Sort: sort.Name, Order: sort.Order, Key: first.SortKey, ID: first.ID, Prev: true})
+		}
+	}
+
+	return ids, next, prev, nil
+}
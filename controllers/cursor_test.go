@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	valid := encodeCursor(pageCursor{Sort: "created", Order: "desc", Key: "2461161.5", ID: 7})
+	tests := []struct {
+		name    string
+		token   string
+		sort    string
+		order   string
+		want    *pageCursor
+		wantErr bool
+	}{
+		{name: "empty is the first page", token: "", sort: "created", order: "desc"},
+		{name: "round trip", token: valid, sort: "created", order: "desc", want: &pageCursor{Sort: "created", Order: "desc", Key: "2461161.5", ID: 7}},
+		{name: "other sort", token: valid, sort: "clicks", order: "desc", wantErr: true},
+		{name: "other order", token: valid, sort: "created", order: "asc", wantErr: true},
+		{name: "not base64", token: "***", sort: "created", order: "desc", wantErr: true},
+		{name: "not JSON", token: "bm9wZQ", sort: "created", order: "desc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.token, tt.sort, tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCursor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestKeysetPage walks every page forwards and back. The clicks are stored
+// with different UTC offsets, so their text doesn't sort by time.
+func TestKeysetPage(t *testing.T) {
+	setupTestDB(t)
+	jakarta := time.FixedZone("WIB", 7*3600)
+	newYork := time.FixedZone("EST", -5*3600)
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		base.In(jakarta),                              // Stored as 19:00+07:00
+		base.Add(time.Hour).In(newYork),               // Stored as 08:00-05:00
+		base.Add(30 * time.Minute),                    // Stored as 12:30+00:00
+		base.Add(time.Hour),                           // Same time as the second one
+		base.Add(2*time.Hour + 123456789).In(jakarta), // With nanoseconds
+		base.Add(2*time.Hour + 123456790).In(newYork), // One nanosecond later
+		base.Add(-24 * time.Hour).In(jakarta),
+	}
+	for _, at := range times {
+		models.DB.Create(&models.Click{URLID: 1, ClickedAt: at})
+	}
+
+	// Expected order: by time, then by id
+	type row struct {
+		id int
+		at time.Time
+	}
+	var all []row
+	for i, at := range times {
+		all = append(all, row{i + 1, at})
+	}
+	for _, order := range []string{"asc", "desc"} {
+		want := append([]row(nil), all...)
+		less := func(a, b row) bool {
+			if !a.at.Equal(b.at) {
+				return a.at.Before(b.at)
+			}
+			return a.id < b.id
+		}
+		for i := range want {
+			for j := i + 1; j < len(want); j++ {
+				if less(want[j], want[i]) == (order == "asc") {
+					want[i], want[j] = want[j], want[i]
+				}
+			}
+		}
+		var wantIDs []int
+		for _, r := range want {
+			wantIDs = append(wantIDs, r.id)
+		}
+
+		t.Run(order, func(t *testing.T) {
+			sort := keysetSort{Name: "clicked_at", Expr: "clicks.clicked_at", Time: true, IDColumn: "clicks.id", Order: order}
+			query := func() *gorm.DB { return models.DB.Model(&models.Click{}) }
+
+			var forwards []int
+			var pages []string // Cursor of each page after the first
+			var cursor *pageCursor
+			for {
+				ids, next, _, err := keysetPage(query(), sort, cursor, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				forwards = append(forwards, ids...)
+				if next == "" {
+					break
+				}
+				pages = append(pages, next)
+				if cursor, err = decodeCursor(next, sort.Name, sort.Order); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(forwards, wantIDs) {
+				t.Fatalf("forwards = %v, want %v", forwards, wantIDs)
+			}
+
+			// Back from the last page, one page at a time
+			ids, _, prev, _ := keysetPage(query(), sort, mustDecode(t, pages[len(pages)-1], sort), 2)
+			backwards := ids
+			for prev != "" {
+				ids, _, prev, _ = keysetPage(query(), sort, mustDecode(t, prev, sort), 2)
+				backwards = append(ids, backwards...)
+			}
+			if !reflect.DeepEqual(backwards, wantIDs) {
+				t.Fatalf("backwards = %v, want %v", backwards, wantIDs)
+			}
+		})
+	}
+}
+
+func mustDecode(t *testing.T, token string, sort keysetSort) *pageCursor {
+	t.Helper()
+	cursor, err := decodeCursor(token, sort.Name, sort.Order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cursor
+}
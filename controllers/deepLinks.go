@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AppLinksConfig lists the apps allowed to open links on each domain, for
+// the apple-app-site-association and assetlinks.json files. The "*" domain
+// applies to hosts without their own entry.
+type AppLinksConfig struct {
+	Domains map[string]DomainAppLinks `json:"domains"`
+}
+
+type DomainAppLinks struct {
+	Apple *struct {
+		AppIDs []string `json:"app_ids"` // <team id>.<bundle id>
+		Paths  []string `json:"paths"`   // Defaults to every path
+	} `json:"apple"`
+	Android []struct {
+		PackageName            string   `json:"package_name"`
+		SHA256CertFingerprints []string `json:"sha256_cert_fingerprints"`
+	} `json:"android"`
+}
+
+var appLinks AppLinksConfig
+
+// LoadAppLinks reads the app links configuration. A missing file leaves the
+// well-known files unserved.
+func LoadAppLinks(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &appLinks)
+}
+
+// requestHost returns the lower-cased host of the request, without port.
+func requestHost(c *gin.Context) string {
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// domainAppLinks returns the configuration for the host of the request.
+func domainAppLinks(c *gin.Context) (DomainAppLinks, bool) {
+	if config, ok := appLinks.Domains[requestHost(c)]; ok {
+		return config, true
+	}
+	config, ok := appLinks.Domains["*"]
+	return config, ok
+}
+
+// AppleAppSiteAssociation serves the universal links file of the domain.
+func AppleAppSiteAssociation(c *gin.Context) {
+	config, ok := domainAppLinks(c)
+	if !ok || config.Apple == nil || len(config.Apple.AppIDs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "No apps configured for this domain",
+		})
+		return
+	}
+
+	paths := config.Apple.Paths
+	if len(paths) == 0 {
+		paths = []string{"*"}
+	}
+	details := make([]gin.H, len(config.Apple.AppIDs))
+	for i, appID := range config.Apple.AppIDs {
+		details[i] = gin.H{"appID": appID, "paths": paths}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"applinks": gin.H{
+			"apps":    []string{},
+			"details": details,
+		},
+	})
+}
+
+// AssetLinks serves the Android App Links file of the domain.
+func AssetLinks(c *gin.Context) {
+	config, ok := domainAppLinks(c)
+	if !ok || len(config.Android) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "No apps configured for this domain",
+		})
+		return
+	}
+
+	statements := make([]gin.H, len(config.Android))
+	for i, app := range config.Android {
+		statements[i] = gin.H{
+			"relation": []string{"delegate_permission/common.handle_all_urls"},
+			"target": gin.H{
+				"namespace":                "android_app",
+				"package_name":             app.PackageName,
+				"sha256_cert_fingerprints": app.SHA256CertFingerprints,
+			},
+		}
+	}
+	c.JSON(http.StatusOK, statements)
+}
+
+// The bounce page opens the app right away. If the page is still visible
+// after a moment the app isn't installed, so it moves on to the fallback.
+var bouncePage = template.Must(template.New("bounce").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Opening…</title>
+<noscript><meta http-equiv="refresh" content="0;url={{.Fallback}}"></noscript>
+</head>
+<body style="font-family: sans-serif; text-align: center; padding: 3em 1em">
+<p>Opening the app…</p>
+<p><a href="{{.App}}">Open in the app</a> · <a href="{{.Fallback}}">Continue</a></p>
+<script>
+(function () {
+  var app = {{.App}}, fallback = {{.Fallback}};
+  window.location.replace(app);
+  setTimeout(function () {
+    if (!document.hidden) {
+      window.location.replace(fallback);
+    }
+  }, 1500);
+})();
+</script>
+</body>
+</html>
+`))
+
+// serveBouncePage sends the visitor to the app, with the store or the web
+// destination as fallback. It serves nothing and returns false when the
+// fallback isn't a web URL, since the page would navigate to it from script.
+func serveBouncePage(c *gin.Context, app models.AppLink, destination string) bool {
+	target := app.AppURI
+	if target == "" {
+		target = app.LinkURL
+	}
+	fallback := app.StoreURL
+	if fallback == "" {
+		fallback = destination
+	}
+	if parsed, err := url.Parse(fallback); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	bouncePage.Execute(c.Writer, gin.H{
+		// App URIs are checked against unsafe schemes when the link is saved
+		"App":      template.URL(target),
+		"Fallback": template.URL(fallback),
+	})
+	return true
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorPagesConfig sets the error pages of each domain, by kind. The "*"
+// domain applies to hosts without their own entry.
+type ErrorPagesConfig struct {
+	Domains map[string]map[string]*DomainErrorPage `json:"domains"`
+}
+
+// DomainErrorPage either redirects or renders a template file, relative to
+// the configuration file.
+type DomainErrorPage struct {
+	RedirectURL  string `json:"redirect_url"`
+	TemplateFile string `json:"template_file"`
+	template     *template.Template
+}
+
+var errorPages ErrorPagesConfig
+
+// LoadErrorPages reads the error pages configuration and parses its
+// templates. A missing file leaves the built-in pages in place.
+func LoadErrorPages(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &errorPages); err != nil {
+		return err
+	}
+
+	for domain, pages := range errorPages.Domains {
+		for kind, page := range pages {
+			if !models.ValidErrorPageKind(kind) {
+				return fmt.Errorf("%s: unknown error page %q", domain, kind)
+			}
+			if page.TemplateFile == "" {
+				continue
+			}
+			text, err := os.ReadFile(filepath.Join(filepath.Dir(path), page.TemplateFile))
+			if err != nil {
+				return err
+			}
+			if page.template, err = models.ParseErrorTemplate(string(text)); err != nil {
+				return fmt.Errorf("%s: %s: %w", domain, kind, err)
+			}
+		}
+	}
+	return nil
+}
+
+// linkFailure is why a visitor can't be sent on.
+type linkFailure struct {
+	Status    int
+	Kind      string
+	Message   string
+	OwnerID   int // User whose error pages apply, 0 when unknown
+	ShortCode string
+	Extra     gin.H // More fields for the JSON body
+}
+
+var builtinErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Message}}</title>
+</head>
+<body style="font-family: sans-serif; text-align: center; padding: 3em 1em; color: #333">
+<h1>{{.Status}}</h1>
+<p>{{.Message}}</p>
+</body>
+</html>
+`))
+
+// respondLinkError answers a visitor whose link can't be followed. API
+// clients get JSON. Browsers get the error page of the link's owner, then
+// the one of the domain, then the built-in one.
+func respondLinkError(c *gin.Context, failure linkFailure) {
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		body := gin.H{
+			"status":  false,
+			"message": failure.Message,
+		}
+		for key, value := range failure.Extra {
+			body[key] = value
+		}
+		c.JSON(failure.Status, body)
+		return
+	}
+
+	data := models.ErrorPageData{
+		Kind:      failure.Kind,
+		Status:    failure.Status,
+		Message:   failure.Message,
+		ShortCode: failure.ShortCode,
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+
+	if failure.OwnerID != 0 {
+		var page models.ErrorPage
+		if models.DB.Where("user_id = ? AND kind = ?", failure.OwnerID, failure.Kind).First(&page).Error == nil {
+			if page.RedirectURL != "" {
+				c.Redirect(http.StatusFound, page.RedirectURL)
+				return
+			}
+			// User templates run sandboxed, so they can't script our domain
+			if tmpl, err := page.ParsedTemplate(); err == nil {
+				if body, err := models.RenderErrorTemplate(tmpl, data); err == nil {
+					c.Header("Content-Security-Policy", "sandbox; default-src 'none'; style-src 'unsafe-inline'; img-src https: data:")
+					c.Status(failure.Status)
+					c.Writer.Write(body)
+					return
+				}
+			}
+		}
+	}
+
+	if page := domainErrorPage(c, failure.Kind); page != nil {
+		if page.RedirectURL != "" {
+			c.Redirect(http.StatusFound, page.RedirectURL)
+			return
+		}
+		if page.template != nil {
+			if body, err := models.RenderErrorTemplate(page.template, data); err == nil {
+				c.Status(failure.Status)
+				c.Writer.Write(body)
+				return
+			}
+		}
+	}
+
+	c.Status(failure.Status)
+	builtinErrorPage.Execute(c.Writer, data)
+}
+
+func domainErrorPage(c *gin.Context, kind string) *DomainErrorPage {
+	if pages, ok := errorPages.Domains[requestHost(c)]; ok && pages[kind] != nil {
+		return pages[kind]
+	}
+	return errorPages.Domains["*"][kind]
+}
+
+// GetErrorPages lists the current user's error pages.
+func GetErrorPages(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var pages []models.ErrorPage
+	models.DB.Where("user_id = ?", userID).Order("kind").Find(&pages)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Error pages retrieved successfully",
+		"data":    pages,
+	})
+}
+
+// SetErrorPage sets the current user's page for one kind of error: a
+// redirect URL or an HTML template.
+func SetErrorPage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	kind := c.Param("kind")
+	if !models.ValidErrorPageKind(kind) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Invalid kind. Use " + strings.Join(models.ErrorPageKinds, ", "),
+		})
+		return
+	}
+
+	var input struct {
+		RedirectURL string `json:"redirect_url" binding:"omitempty,url"`
+		Template    string `json:"template"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if (input.RedirectURL == "") == (input.Template == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Set either redirect_url or template",
+		})
+		return
+	}
+	if input.RedirectURL != "" && !strings.HasPrefix(input.RedirectURL, "https://") && !strings.HasPrefix(input.RedirectURL, "http://") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "redirect_url must be an http or https URL",
+		})
+		return
+	}
+	if input.Template != "" {
+		if _, err := models.ParseErrorTemplate(input.Template); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": "Invalid template: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	page := models.ErrorPage{UserID: int(userID), Kind: kind}
+	models.DB.Where(&page).First(&page)
+	page.RedirectURL = input.RedirectURL
+	page.Template = input.Template
+
+	if err := models.DB.Save(&page).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to save error page",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Error page saved successfully",
+		"data":    page,
+	})
+}
+
+// DeleteErrorPage goes back to the domain's or the built-in page.
+func DeleteErrorPage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var page models.ErrorPage
+	if err := models.DB.Where("user_id = ? AND kind = ?", userID, c.Param("kind")).First(&page).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Error page not found",
+		})
+		return
+	}
+
+	result := models.DB.Delete(&page)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to delete error page",
+		})
+		return
+	}
+	models.ForgetErrorTemplate(page.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Error page deleted successfully",
+	})
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondLinkError(t *testing.T) {
+	setupTestDB(t)
+	pages := []models.ErrorPage{
+		{UserID: 1, Kind: models.ErrorPageNotFound, Template: "<p>custom {{.ShortCode}}</p>"},
+		{UserID: 1, Kind: models.ErrorPageExpired, RedirectURL: "https://example.com/expired"},
+		// Saved before templates were restricted, it must not run
+		{UserID: 1, Kind: models.ErrorPageDisabled, Template: "{{range 1000000000}}x{{end}}"},
+	}
+	if err := models.DB.Create(&pages).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	domainTemplate := template.Must(template.New("error").Parse("<p>domain {{.Status}}</p>"))
+	errorPages = ErrorPagesConfig{Domains: map[string]map[string]*DomainErrorPage{
+		"*": {models.ErrorPageNotFound: {template: domainTemplate}},
+	}}
+	t.Cleanup(func() { errorPages = ErrorPagesConfig{} })
+
+	tests := []struct {
+		name         string
+		accept       string
+		failure      linkFailure
+		wantStatus   int
+		wantType     string
+		wantBody     string
+		wantLocation string
+		wantCSP      bool
+	}{
+		{
+			name:       "JSON without an Accept header",
+			failure:    linkFailure{Status: 404, Kind: models.ErrorPageNotFound, Message: "Short URL not found", OwnerID: 1, Extra: gin.H{"short_code": "abc"}},
+			wantStatus: 404,
+			wantType:   "application/json",
+			wantBody:   `"short_code":"abc"`,
+		},
+		{
+			name:       "JSON when preferred over HTML",
+			accept:     "application/json, text/html;q=0.5",
+			failure:    linkFailure{Status: 410, Kind: models.ErrorPageExpired, Message: "Short URL has expired", OwnerID: 1},
+			wantStatus: 410,
+			wantType:   "application/json",
+			wantBody:   `"message":"Short URL has expired"`,
+		},
+		{
+			name:       "owner's template",
+			accept:     "text/html",
+			failure:    linkFailure{Status: 404, Kind: models.ErrorPageNotFound, Message: "Short URL not found", OwnerID: 1, ShortCode: "abc"},
+			wantStatus: 404,
+			wantType:   "text/html",
+			wantBody:   "<p>custom abc</p>",
+			wantCSP:    true,
+		},
+		{
+			name:         "owner's redirect",
+			accept:       "text/html",
+			failure:      linkFailure{Status: 410, Kind: models.ErrorPageExpired, Message: "Short URL has expired", OwnerID: 1},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://example.com/expired",
+		},
+		{
+			name:       "owner's invalid template falls back to the built-in page",
+			accept:     "text/html",
+			failure:    linkFailure{Status: 403, Kind: models.ErrorPageDisabled, Message: "Short URL is disabled", OwnerID: 1},
+			wantStatus: 403,
+			wantType:   "text/html",
+			wantBody:   "<p>Short URL is disabled</p>",
+		},
+		{
+			name:       "domain's template without an owner page",
+			accept:     "text/html",
+			failure:    linkFailure{Status: 404, Kind: models.ErrorPageNotFound, Message: "Short URL not found", OwnerID: 2},
+			wantStatus: 404,
+			wantType:   "text/html",
+			wantBody:   "<p>domain 404</p>",
+		},
+		{
+			name:       "built-in page",
+			accept:     "text/html,application/xhtml+xml",
+			failure:    linkFailure{Status: 410, Kind: models.ErrorPageExpired, Message: "Short URL has expired"},
+			wantStatus: 410,
+			wantType:   "text/html",
+			wantBody:   "<h1>410</h1>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/abc", nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+
+			respondLinkError(c, tt.failure)
+			c.Writer.WriteHeaderNow() // Done by the engine after the handlers
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); tt.wantType != "" && !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			if got := w.Header().Get("Content-Security-Policy") != ""; got != tt.wantCSP {
+				t.Errorf("Content-Security-Policy set = %v, want %v", got, tt.wantCSP)
+			}
+			if tt.wantType == "application/json" {
+				var body map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["status"] != false {
+					t.Errorf("body = %q, want a JSON error", w.Body.String())
+				}
+			}
+		})
+	}
+}
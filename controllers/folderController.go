@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FolderInput struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *int   `json:"parent_id"`
+}
+
+func CreateFolder(c *gin.Context) {
+	var input FolderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	if input.ParentID != nil && !workspaceHasFolder(membership.WorkspaceID, *input.ParentID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Parent folder not found",
+		})
+		return
+	}
+
+	folder := models.Folder{
+		UserID:      int(userID),
+		WorkspaceID: membership.WorkspaceID,
+		Name:        strings.TrimSpace(input.Name),
+		ParentID:    input.ParentID,
+	}
+
+	if err := models.DB.Create(&folder).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to create folder",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Folder created successfully",
+		"data":    folder,
+	})
+}
+
+// GetFolders returns the workspace's folders as a flat list with link counts; the
+// hierarchy is given by parent_id.
+func GetFolders(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var folders []struct {
+		models.Folder
+		LinkCount int64 `json:"link_count"`
+	}
+	if err := models.DB.Model(&models.Folder{}).
+		Select("folders.*, (SELECT COUNT(*) FROM urls WHERE urls.folder_id = folders.id AND urls.deleted_at IS NULL) AS link_count").
+		Where("workspace_id = ?", membership.WorkspaceID).
+		Order("name").
+		Scan(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve folders",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Folders retrieved successfully",
+		"data":    folders,
+	})
+}
+
+func UpdateFolder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var folder models.Folder
+	if err := models.DB.Where("id = ? AND workspace_id = ?", c.Param("id"), membership.WorkspaceID).First(&folder).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Folder not found",
+		})
+		return
+	}
+
+	var input FolderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if input.ParentID != nil {
+		if !workspaceHasFolder(membership.WorkspaceID, *input.ParentID) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": "Parent folder not found",
+			})
+			return
+		}
+
+		// A folder can't be moved into itself or one of its subfolders
+		for _, id := range models.FolderWithDescendants(membership.WorkspaceID, folder.ID) {
+			if id == *input.ParentID {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"status":  false,
+					"message": "A folder cannot be moved into itself or its subfolders",
+				})
+				return
+			}
+		}
+	}
+
+	folder.Name = strings.TrimSpace(input.Name)
+	folder.ParentID = input.ParentID
+
+	if err := models.DB.Save(&folder).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update folder",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Folder updated successfully",
+		"data":    folder,
+	})
+}
+
+// DeleteFolder removes a folder. Its links (those in the trash too) and
+// subfolders move up to the folder's parent, nothing else is deleted.
+func DeleteFolder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var folder models.Folder
+	if err := models.DB.Where("id = ? AND workspace_id = ?", c.Param("id"), membership.WorkspaceID).First(&folder).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Folder not found",
+		})
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.URL{}).Where("folder_id = ?", folder.ID).Update("folder_id", folder.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Folder{}).Where("parent_id = ?", folder.ID).Update("parent_id", folder.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&folder).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to delete folder",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Folder deleted successfully",
+	})
+}
+
+func workspaceHasFolder(workspaceID, folderID int) bool {
+	var count int64
+	models.DB.Model(&models.Folder{}).Where("id = ? AND workspace_id = ?", folderID, workspaceID).Count(&count)
+	return count > 0
+}
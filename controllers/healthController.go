@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// linkHealth is the health of a link's destination as shown in listings.
+func linkHealth(url models.URL) gin.H {
+	return gin.H{
+		"status":      url.HealthStatus,
+		"status_code": url.HealthStatusCode,
+		"failures":    url.HealthFailures,
+		"checked_at":  url.HealthCheckedAt,
+	}
+}
+
+// GetURLHealth returns the health of a link's destination with its latest
+// checks, and the uptime and average response time over the kept history.
+func GetURLHealth(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var url models.URL
+	if err := models.DB.First(&url, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URL not found",
+		})
+		return
+	}
+
+	if !authorizeURL(c, userID, url, models.RoleViewer) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	var checks []models.HealthCheck
+	models.DB.Where("url_id = ?", url.ID).Order("checked_at DESC, id DESC").Limit(limit).Find(&checks)
+
+	var summary struct {
+		Total           int64
+		Healthy         int64
+		AvgResponseTime float64
+	}
+	models.DB.Model(&models.HealthCheck{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN healthy THEN 1 ELSE 0 END), 0) AS healthy, COALESCE(AVG(response_time_ms), 0) AS avg_response_time").
+		Where("url_id = ? AND checked_at >= ?", url.ID, time.Now().Add(-models.HealthCheckRetention)).
+		Scan(&summary)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "URL health retrieved successfully",
+		"data": gin.H{
+			"url_id":               url.ID,
+			"health":               linkHealth(url),
+			"checks_count":         summary.Total,
+			"uptime":               rate(summary.Healthy, summary.Total),
+			"avg_response_time_ms": summary.AvgResponseTime,
+			"checks":               checks,
+		},
+	})
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errFolderNotFound = errors.New("folder not found")
+
+// linkFilter narrows the links a list or analytics query covers. Conditions
+// are written against the urls table.
+type linkFilter struct {
+	ShortCode string
+	Tag       string
+	Folder    string // folder ID (subfolders included) or "none" for unfiled links
+	Campaign  string // UTM campaign
+	folderIDs []int
+}
+
+// parseLinkFilter reads the url, tag, folder and utm_campaign query parameters.
+func parseLinkFilter(c *gin.Context, workspaceID int) (linkFilter, error) {
+	filter := linkFilter{
+		ShortCode: c.Query("url"),
+		Tag:       c.Query("tag"),
+		Folder:    c.Query("folder"),
+		Campaign:  c.Query("utm_campaign"),
+	}
+
+	if filter.Folder != "" && filter.Folder != "none" {
+		folderID, err := strconv.Atoi(filter.Folder)
+		if err != nil || !workspaceHasFolder(workspaceID, folderID) {
+			return filter, errFolderNotFound
+		}
+		filter.folderIDs = models.FolderWithDescendants(workspaceID, folderID)
+	}
+
+	return filter, nil
+}
+
+func (f linkFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.ShortCode != "" {
+		query = query.Where("urls.short_code = ?", f.ShortCode)
+	}
+	if f.Tag != "" {
+		query = query.Where("urls.id IN (SELECT url_tags.url_id FROM url_tags JOIN tags ON tags.id = url_tags.tag_id WHERE tags.name = ? AND tags.workspace_id = urls.workspace_id)", f.Tag)
+	}
+	if f.Campaign != "" {
+		query = query.Where("urls.utm_campaign = ?", f.Campaign)
+	}
+	if f.Folder == "none" {
+		query = query.Where("urls.folder_id IS NULL")
+	} else if f.folderIDs != nil {
+		query = query.Where("urls.folder_id IN ?", f.folderIDs)
+	}
+	return query
+}
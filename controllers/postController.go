@@ -2,16 +2,687 @@ package controllers
 
 import (
 	"backend-go/models"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func FindPosts(c *gin.Context) {
+// Link-in-bio pages are stored as posts. Each block links to one of the
+// short links the user can see in their workspaces.
+
+type PageInput struct {
+	Slug      *string `json:"slug"`
+	Title     *string `json:"title" binding:"omitempty,max=100"`
+	Body      *string `json:"body" binding:"omitempty,max=500"`
+	Theme     *string `json:"theme"`
+	Published *bool   `json:"published"`
+}
+
+type BlockInput struct {
+	URLID       int     `json:"url_id"`
+	ShortCode   string  `json:"short_code"` // Instead of url_id
+	Title       *string `json:"title" binding:"omitempty,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=300"`
+}
+
+// GetPages lists the link-in-bio pages of the current user.
+func GetPages(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	var posts []models.Post
-	models.DB.Find(&posts)
-	c.JSON(200, gin.H{
+	models.DB.Where("user_id = ?", userID).Preload("Blocks", byPosition).Order("slug").Find(&posts)
+
+	pages := make([]gin.H, len(posts))
+	for i, post := range posts {
+		pages[i] = pageResponse(post)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Pages retrieved successfully",
+		"data":    pages,
+	})
+}
+
+func GetPage(c *gin.Context) {
+	post, ok := findUserPost(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Page retrieved successfully",
+		"data":    pageResponse(post),
+	})
+}
+
+func CreatePage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input PageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var count int64
+	models.DB.Model(&models.Post{}).Where("user_id = ?", userID).Count(&count)
+	if count >= models.MaxPostsPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Page limit reached",
+		})
+		return
+	}
+
+	post := models.Post{UserID: int(userID), Theme: "light", Published: true}
+	if !applyPageInput(c, &post, input) {
+		return
+	}
+
+	if err := models.DB.Create(&post).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to create page",
+		})
+		return
+	}
+	post.Blocks = []models.PostBlock{}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Page created successfully",
+		"data":    pageResponse(post),
+	})
+}
+
+func UpdatePage(c *gin.Context) {
+	post, ok := findUserPost(c)
+	if !ok {
+		return
+	}
+
+	var input PageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if !applyPageInput(c, &post, input) {
+		return
+	}
+
+	if err := models.DB.Omit("Blocks").Save(&post).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update page",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Page updated successfully",
+		"data":    pageResponse(post),
+	})
+}
+
+func DeletePage(c *gin.Context) {
+	post, ok := findUserPost(c)
+	if !ok {
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", post.ID).Delete(&models.PostBlock{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&post).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to delete page",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Page deleted successfully",
+	})
+}
+
+// CreateBlock appends a short link to a page.
+func CreateBlock(c *gin.Context) {
+	post, ok := findUserPost(c)
+	if !ok {
+		return
+	}
+
+	var input BlockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if len(post.Blocks) >= models.MaxPostBlocks {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Block limit reached",
+		})
+		return
+	}
+
+	url, ok := findBlockURL(c, post.UserID, input)
+	if !ok {
+		return
+	}
+
+	block := models.PostBlock{PostID: post.ID, URLID: url.ID, Position: len(post.Blocks)}
+	if input.Title != nil {
+		block.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		block.Description = strings.TrimSpace(*input.Description)
+	}
+
+	if err := models.DB.Create(&block).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to create block",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Block created successfully",
+		"data":    block,
+	})
+}
+
+func UpdateBlock(c *gin.Context) {
+	post, ok := findUserPost(c)
+	if !ok {
+		return
+	}
+
+	block, ok := findPostBlock(c, post)
+	if !ok {
+		return
+	}
+
+	var input BlockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if input.URLID != 0 || input.ShortCode != "" {
+		url, ok := findBlockURL(c, post.UserID, input)
+		if !ok {
+			return
+		}
+		block.URLID = url.ID
+	}
+	if input.Title != nil {
+		block.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		block.Description = strings.TrimSpace(*input.Description)
+	}
+
+	if err := models.DB.Save(&block).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update block",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
-		"message": "Posts retrieved successfully",
-		"posts":   posts,
+		"message": "Block updated successfully",
+		"data":    block,
 	})
 }
+
+func DeleteBlock(c *gin.Context) {
+	post, ok := findUserPost(c)
+	if !ok {
+		return
+	}
+
+	block, ok := findPostBlock(c, post)
+	if !ok {
+		return
+	}
+
+	// Close the gap so positions stay contiguous
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&block).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.PostBlock{}).
+			Where("post_id = ? AND position > ?", post.ID, block.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to delete block",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Block deleted successfully",
+	})
+}
+
+// ReorderBlocks sets the order of the blocks of a page. Every block of the
+// page must be listed once.
+func ReorderBlocks(c *gin.Context) {
+	post, ok := findUserPost(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		BlockIDs []int `json:"block_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	positions := make(map[int]int, len(input.BlockIDs))
+	for i, id := range input.BlockIDs {
+		positions[id] = i
+	}
+	valid := len(positions) == len(input.BlockIDs) && len(positions) == len(post.Blocks)
+	for _, block := range post.Blocks {
+		if _, ok := positions[block.ID]; !ok {
+			valid = false
+		}
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "block_ids must list every block of the page once",
+		})
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		for id, position := range positions {
+			if err := tx.Model(&models.PostBlock{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to reorder blocks",
+		})
+		return
+	}
+
+	models.DB.Where("post_id = ?", post.ID).Order("position").Find(&post.Blocks)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Blocks reordered successfully",
+		"data":    pageResponse(post),
+	})
+}
+
+// applyPageInput validates the page fields of the request and sets them on
+// the post.
+func applyPageInput(c *gin.Context, post *models.Post, input PageInput) bool {
+	if input.Slug != nil {
+		slug := strings.ToLower(strings.TrimSpace(*input.Slug))
+		if !models.ValidPostSlug(slug) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": "Slug may only contain lower-case letters, digits and dashes",
+			})
+			return false
+		}
+
+		var existing models.Post
+		if err := models.DB.Where("user_id = ? AND slug = ? AND id <> ?", post.UserID, slug, post.ID).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  false,
+				"message": "Slug already in use",
+			})
+			return false
+		}
+		post.Slug = slug
+	}
+	if input.Theme != nil {
+		if !models.ValidPostTheme(*input.Theme) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": "Invalid theme. Use " + strings.Join(models.PostThemes, ", "),
+			})
+			return false
+		}
+		post.Theme = *input.Theme
+	}
+	if input.Title != nil {
+		post.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Body != nil {
+		post.Body = strings.TrimSpace(*input.Body)
+	}
+	if input.Published != nil {
+		post.Published = *input.Published
+	}
+	return true
+}
+
+// findUserPost loads the page in the id parameter with its blocks, if it
+// belongs to the current user.
+func findUserPost(c *gin.Context) (models.Post, bool) {
+	var post models.Post
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return post, false
+	}
+
+	if err := models.DB.Preload("Blocks", byPosition).Where("user_id = ?", userID).First(&post, paramID(c, "id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Page not found",
+		})
+		return post, false
+	}
+	return post, true
+}
+
+func findPostBlock(c *gin.Context, post models.Post) (models.PostBlock, bool) {
+	blockID := paramID(c, "blockId")
+	for _, block := range post.Blocks {
+		if block.ID == blockID {
+			return block, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"status":  false,
+		"message": "Block not found",
+	})
+	return models.PostBlock{}, false
+}
+
+// findBlockURL loads the short link of a block, by id or short code. The
+// page owner must be able to see the link.
+func findBlockURL(c *gin.Context, userID int, input BlockInput) (models.URL, bool) {
+	var url models.URL
+	query := models.DB
+	if input.URLID != 0 {
+		query = query.Where("id = ?", input.URLID)
+	} else if input.ShortCode != "" {
+		query = query.Where("short_code = ?", input.ShortCode)
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "url_id or short_code is required",
+		})
+		return url, false
+	}
+
+	if err := query.First(&url).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URL not found",
+		})
+		return url, false
+	}
+	if !authorizeURL(c, uint(userID), url, models.RoleViewer) {
+		return url, false
+	}
+	return url, true
+}
+
+// pageResponse describes a page with its public path and the clicks of each
+// block.
+func pageResponse(post models.Post) gin.H {
+	var user models.User
+	models.DB.Select("username").First(&user, post.UserID)
+
+	blockIDs := make([]int, len(post.Blocks))
+	for i, block := range post.Blocks {
+		blockIDs[i] = block.ID
+	}
+	var rows []struct {
+		BlockID int
+		Clicks  int64
+	}
+	if len(blockIDs) > 0 {
+		models.DB.Model(&models.Click{}).
+			Select("block_id, COUNT(*) AS clicks").
+			Where("block_id IN ?", blockIDs).
+			Group("block_id").
+			Scan(&rows)
+	}
+	clicks := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		clicks[row.BlockID] = row.Clicks
+	}
+
+	blocks := make([]gin.H, len(post.Blocks))
+	for i, block := range post.Blocks {
+		blocks[i] = gin.H{
+			"id":          block.ID,
+			"position":    block.Position,
+			"url_id":      block.URLID,
+			"title":       block.Title,
+			"description": block.Description,
+			"clicks":      clicks[block.ID],
+		}
+	}
+
+	return gin.H{
+		"id":         post.ID,
+		"slug":       post.Slug,
+		"path":       pagePath(user.Username, post.Slug),
+		"title":      post.Title,
+		"body":       post.Body,
+		"theme":      post.Theme,
+		"published":  post.Published,
+		"blocks":     blocks,
+		"created_at": post.CreatedAt,
+		"updated_at": post.UpdatedAt,
+	}
+}
+
+func pagePath(username, slug string) string {
+	if slug == "" {
+		return "/@" + username
+	}
+	return "/@" + username + "/" + slug
+}
+
+// findPublishedPost loads a published page by its owner's username and slug.
+func findPublishedPost(username, slug string) (models.Post, models.User, error) {
+	var post models.Post
+	var user models.User
+	if err := models.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		return post, user, err
+	}
+	err := models.DB.Where("user_id = ? AND slug = ? AND published = ?", user.ID, slug, true).
+		Preload("Blocks", byPosition).
+		First(&post).Error
+	return post, user, err
+}
+
+var pageThemes = map[string]template.CSS{
+	"light":   "body{background:#f5f5f7;color:#1d1d1f}a.block{background:#fff;color:#1d1d1f;box-shadow:0 1px 3px rgba(0,0,0,.12)}",
+	"dark":    "body{background:#111;color:#f5f5f7}a.block{background:#222;color:#f5f5f7}",
+	"minimal": "body{background:#fff;color:#000}a.block{background:#fff;color:#000;border:1px solid #000}",
+}
+
+var bioPage = template.Must(template.New("bio").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{if .Body}}<meta name="description" content="{{.Body}}">{{end}}
+<style>
+body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;margin:0;padding:3em 1em}
+main{max-width:36em;margin:0 auto;text-align:center}
+a.block{display:block;margin:1em 0;padding:1em;border-radius:.75em;text-decoration:none}
+a.block span{display:block;font-size:.85em;opacity:.7;margin-top:.25em}
+{{.Theme}}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+{{if .Body}}<p>{{.Body}}</p>{{end}}
+{{range .Blocks}}<a class="block" href="{{.Href}}" rel="noopener">{{.Title}}{{if .Description}}<span>{{.Description}}</span>{{end}}</a>
+{{end}}</main>
+</body>
+</html>
+`))
+
+// visibleToPageOwner narrows a URL query to the links the page's owner can
+// still see, so blocks stop working once they leave the link's workspace.
+func visibleToPageOwner(query *gorm.DB, userID int) *gorm.DB {
+	return query.Where("urls.workspace_id IN (SELECT workspace_id FROM memberships WHERE user_id = ? AND role IN ?)",
+		userID, models.RolesAtLeast(models.RoleViewer))
+}
+
+// RenderPage serves a published link-in-bio page at /@username or
+// /@username/slug. Blocks whose link is gone, expired, not live yet or no
+// longer visible to the page's owner are left out.
+func RenderPage(c *gin.Context) {
+	username := c.Param("username")
+	post, user, err := findPublishedPost(username, c.Param("slug"))
+	if err != nil {
+		respondLinkError(c, linkFailure{
+			Status:  http.StatusNotFound,
+			Kind:    models.ErrorPageNotFound,
+			Message: "Page not found",
+			OwnerID: user.ID,
+		})
+		return
+	}
+
+	urlIDs := make([]int, len(post.Blocks))
+	for i, block := range post.Blocks {
+		urlIDs[i] = block.URLID
+	}
+	var urls []models.URL
+	visibleToPageOwner(models.DB.Where("urls.id IN ?", urlIDs), post.UserID).Find(&urls)
+	byID := make(map[int]models.URL, len(urls))
+	for _, url := range urls {
+		byID[url.ID] = url
+	}
+
+	type blockView struct {
+		Href, Title, Description string
+	}
+	now := time.Now()
+	var blocks []blockView
+	for _, block := range post.Blocks {
+		url, ok := byID[block.URLID]
+		if !ok || url.Blocked || url.Disabled || url.IsExpired(now) || !url.IsActive(now) {
+			continue
+		}
+		view := blockView{
+			Href:        pagePath(user.Username, "") + "/b/" + strconv.Itoa(block.ID),
+			Title:       block.Title,
+			Description: block.Description,
+		}
+		if view.Title == "" {
+			view.Title = url.Title
+		}
+		if view.Title == "" {
+			view.Title = url.ShortCode
+		}
+		if view.Description == "" {
+			view.Description = url.Notes
+		}
+		blocks = append(blocks, view)
+	}
+
+	title := post.Title
+	if title == "" {
+		title = "@" + user.Username
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	bioPage.Execute(c.Writer, gin.H{
+		"Title":  title,
+		"Body":   post.Body,
+		"Theme":  pageThemes[post.Theme],
+		"Blocks": blocks,
+	})
+}
+
+// PageBlockClick follows a block of a published page through the normal
+// redirect, recording which block the click came from. Links the page's
+// owner can no longer see are not found.
+func PageBlockClick(c *gin.Context) {
+	var block models.PostBlock
+	var post models.Post
+	var user models.User
+	err := models.DB.First(&block, paramID(c, "blockId")).Error
+	if err == nil {
+		err = models.DB.Where("id = ? AND published = ?", block.PostID, true).First(&post).Error
+	}
+	if err == nil {
+		err = models.DB.Where("id = ? AND username = ?", post.UserID, c.Param("username")).First(&user).Error
+	}
+
+	var url models.URL
+	if err == nil {
+		err = visibleToPageOwner(models.DB.Where("urls.id = ?", block.URLID), post.UserID).First(&url).Error
+	}
+	if err != nil {
+		respondLinkError(c, linkFailure{
+			Status:  http.StatusNotFound,
+			Kind:    models.ErrorPageNotFound,
+			Message: "Short URL not found",
+			OwnerID: user.ID,
+		})
+		return
+	}
+
+	redirectLink(c, url, nil, &block.ID)
+}
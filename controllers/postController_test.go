@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPageBlocksFollowMembership(t *testing.T) {
+	setupTestDB(t)
+	models.DB.Create(&models.User{Username: "ana", Email: "ana@example.com"})
+	models.DB.Create(&[]models.Membership{
+		{WorkspaceID: 1, UserID: 1, Role: models.RoleViewer},
+		{WorkspaceID: 2, UserID: 1, Role: models.RoleOwner},
+	})
+	urls := []models.URL{
+		{ShortCode: "team", Title: "Team link", OriginalURL: "https://team.example.com", UserID: 2, WorkspaceID: 1},
+		{ShortCode: "mine", Title: "My link", OriginalURL: "https://mine.example.com", UserID: 1, WorkspaceID: 2},
+	}
+	models.DB.Create(&urls)
+	post := models.Post{UserID: 1, Published: true, Blocks: []models.PostBlock{
+		{Position: 0, URLID: urls[0].ID},
+		{Position: 1, URLID: urls[1].ID},
+	}}
+	models.DB.Create(&post)
+
+	r := gin.New()
+	r.GET("/@:username", RenderPage)
+	r.GET("/@:username/b/:blockId", PageBlockClick)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "text/html")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	blockPath := func(i int) string {
+		return "/@ana/b/" + strconv.Itoa(post.Blocks[i].ID)
+	}
+
+	steps := []struct {
+		name         string
+		before       func()
+		wantTeam     bool
+		wantClick    int
+		wantOwn      bool
+		wantOwnClick int
+	}{
+		{
+			name:         "member of the workspace",
+			wantTeam:     true,
+			wantClick:    http.StatusMovedPermanently,
+			wantOwn:      true,
+			wantOwnClick: http.StatusMovedPermanently,
+		},
+		{
+			name:         "removed from the workspace",
+			before:       func() { models.DB.Where("workspace_id = ? AND user_id = ?", 1, 1).Delete(&models.Membership{}) },
+			wantTeam:     false,
+			wantClick:    http.StatusNotFound,
+			wantOwn:      true,
+			wantOwnClick: http.StatusMovedPermanently,
+		},
+		{
+			name:         "own link disabled",
+			before:       func() { models.DB.Model(&urls[1]).Update("disabled", true) },
+			wantTeam:     false,
+			wantClick:    http.StatusNotFound,
+			wantOwn:      false,
+			wantOwnClick: http.StatusGone,
+		},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.before != nil {
+				step.before()
+			}
+
+			page := get("/@ana")
+			if page.Code != http.StatusOK {
+				t.Fatalf("page status = %d", page.Code)
+			}
+			if got := strings.Contains(page.Body.String(), "Team link"); got != step.wantTeam {
+				t.Errorf("team block shown = %v, want %v", got, step.wantTeam)
+			}
+			if got := strings.Contains(page.Body.String(), "My link"); got != step.wantOwn {
+				t.Errorf("own block shown = %v, want %v", got, step.wantOwn)
+			}
+
+			if w := get(blockPath(0)); w.Code != step.wantClick {
+				t.Errorf("team block click status = %d, want %d", w.Code, step.wantClick)
+			}
+			if w := get(blockPath(1)); w.Code != step.wantOwnClick {
+				t.Errorf("own block click status = %d, want %d", w.Code, step.wantOwnClick)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"backend-go/redirects"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resolver follows the redirects of destinations when links are saved. Nil
+// leaves them unchecked.
+var Resolver *redirects.Resolver
+
+const resolveTimeout = 15 * time.Second
+
+// followRedirects resolves the destination of a link before it is saved and
+// records where it ends up. With unwrap, a destination that redirects to a
+// working page is replaced by that page. It answers 400 and returns false
+// when the destination loops back through our short links. Destinations
+// that can't be reached are accepted, they may be down for a moment.
+func followRedirects(c *gin.Context, url *models.URL, unwrap bool) (redirects.Chain, bool) {
+	if Resolver == nil {
+		return redirects.Chain{}, true
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), resolveTimeout)
+	defer cancel()
+	chain, err := Resolver.Resolve(ctx, *url)
+	if errors.Is(err, redirects.ErrLoop) && chain.ThroughOwnHosts() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":         false,
+			"message":        "The destination redirects back to itself through our short links",
+			"redirect_chain": chain.Hops,
+		})
+		return chain, false
+	}
+
+	final := chain.Final()
+	url.FinalURL = final.URL
+	url.RedirectHops = chain.Length()
+
+	// Templated destinations depend on the visitor's path, they stay as they are
+	templated := url.MatchPrefix || redirects.StartURL(*url) != url.OriginalURL
+	if unwrap && err == nil && chain.Length() > 0 && !final.Own && !templated &&
+		final.StatusCode >= 200 && final.StatusCode < 300 {
+		url.OriginalURL = final.URL
+		url.Domain = models.DomainOf(final.URL)
+		url.RedirectHops = 0
+	}
+	return chain, true
+}
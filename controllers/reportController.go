@@ -0,0 +1,485 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"backend-go/notify"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var reportForm = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Report a link</title>
+</head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 0 auto; padding: 3em 1em; color: #333">
+{{if .Sent}}
+<h1>Thank you</h1>
+<p>Your report about <code>/{{.ShortCode}}</code> was sent. We review every report.</p>
+{{else}}
+<h1>Report a link</h1>
+<p>Tell us why <code>/{{.ShortCode}}</code> is harmful.</p>
+<form method="post" action="{{.Action}}">
+<p><label>Reason<br>
+<select name="reason" required>
+{{range .Reasons}}<option value="{{.}}">{{.}}</option>
+{{end}}</select></label></p>
+<p><label>Details<br><textarea name="details" rows="5" maxlength="2000" style="width: 100%"></textarea></label></p>
+<p><label>Your email (optional)<br><input type="email" name="reporter_email" style="width: 100%"></label></p>
+<p><button type="submit">Send report</button></p>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+var blockedPage = template.Must(template.New("blocked").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Warning: blocked link</title>
+</head>
+<body style="font-family: sans-serif; text-align: center; padding: 3em 1em; color: #333">
+<h1 style="color: #b00020">Warning</h1>
+<p>The link <code>/{{.ShortCode}}</code> was reported as phishing or abuse and has been blocked.</p>
+<p>Its destination may try to steal your passwords or personal information, so we don't send visitors there.</p>
+</body>
+</html>
+`))
+
+// findReportedLink returns the link of a report path, /report/<short code>.
+func findReportedLink(c *gin.Context) (models.URL, bool) {
+	shortCode := strings.Trim(c.Param("shortCode"), "/")
+	var url models.URL
+	if shortCode == "" || models.DB.Where("short_code = ?", shortCode).First(&url).Error != nil {
+		respondLinkError(c, linkFailure{
+			Status:    http.StatusNotFound,
+			Kind:      models.ErrorPageNotFound,
+			Message:   "Short URL not found",
+			ShortCode: shortCode,
+		})
+		return url, false
+	}
+	return url, true
+}
+
+// ReportForm serves the abuse report form of a link.
+func ReportForm(c *gin.Context) {
+	url, ok := findReportedLink(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	reportForm.Execute(c.Writer, gin.H{
+		"ShortCode": url.ShortCode,
+		"Action":    "/report/" + url.ShortCode,
+		"Reasons":   models.ReportReasons,
+	})
+}
+
+// CreateReport records an abuse report from the form or as JSON. Links
+// reported from AutoBlockThreshold networks in a day are blocked until an
+// admin reviews them, unless an admin recently found them fine.
+func CreateReport(c *gin.Context) {
+	url, ok := findReportedLink(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason        string `json:"reason" form:"reason" binding:"required"`
+		Details       string `json:"details" form:"details" binding:"max=2000"`
+		ReporterEmail string `json:"reporter_email" form:"reporter_email" binding:"omitempty,email,max=255"`
+	}
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if !models.ValidReportReason(input.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Invalid reason. Use " + strings.Join(models.ReportReasons, ", "),
+		})
+		return
+	}
+
+	// One pending report per reporter and link. ClientIP only honours
+	// X-Forwarded-For from TRUSTED_PROXIES.
+	now := time.Now()
+	reporterHash, err := models.HashVisitor(c.ClientIP(), "", now)
+	var networkHash string
+	if err == nil {
+		networkHash, err = models.HashVisitor(models.NetworkOf(c.ClientIP()), "", now)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to save report",
+		})
+		return
+	}
+
+	report := models.Report{
+		URLID:         url.ID,
+		Reason:        input.Reason,
+		Details:       input.Details,
+		ReporterEmail: input.ReporterEmail,
+		ReporterHash:  reporterHash,
+		NetworkHash:   networkHash,
+	}
+	created, networks, err := models.CreateReport(&report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to save report",
+		})
+		return
+	}
+
+	if created && !url.Blocked && networks >= models.AutoBlockThreshold && !models.RecentlyDismissed(url.ID) {
+		reason := fmt.Sprintf("Reported from %d networks, waiting for review", networks)
+		if err := blockLink(&url, reason, nil); err != nil {
+			fmt.Printf("Failed to block link %d: %v\n", url.ID, err)
+		}
+	}
+
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		c.Header("Cache-Control", "no-store")
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		reportForm.Execute(c.Writer, gin.H{"Sent": true, "ShortCode": url.ShortCode})
+		return
+	}
+
+	status, message := http.StatusCreated, "Report sent successfully"
+	if !created {
+		status, message = http.StatusOK, "You already reported this link"
+	}
+	c.JSON(status, gin.H{
+		"status":  true,
+		"message": message,
+	})
+}
+
+// respondBlocked warns the visitor of a blocked link. The owner's error
+// pages don't apply, they could send visitors on to the destination.
+func respondBlocked(c *gin.Context, url models.URL) {
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "Short URL was blocked after abuse reports",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusForbidden)
+	blockedPage.Execute(c.Writer, gin.H{"ShortCode": url.ShortCode})
+}
+
+// blockLink blocks a link and tells its owner. reviewerID is the admin who
+// blocked it, nil when reports did; the pending reports are closed when an
+// admin blocks.
+func blockLink(url *models.URL, reason string, reviewerID *int) error {
+	before := auditURLFields(*url)
+	now := time.Now()
+	url.Blocked = true
+	url.BlockedReason = reason
+	url.BlockedAt = &now
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.URL{}).Where("id = ?", url.ID).UpdateColumns(map[string]interface{}{
+			"blocked":        true,
+			"blocked_reason": reason,
+			"blocked_at":     now,
+		}).Error; err != nil {
+			return err
+		}
+		if reviewerID == nil {
+			return nil
+		}
+		return models.ReviewReports(tx, url.ID, models.ReportActioned, *reviewerID)
+	})
+	if err != nil {
+		return err
+	}
+
+	recordModerationAudit(reviewerID, models.AuditURLBlocked, *url, before)
+	notifyOwner(*url, models.EventLinkBlocked, "Your link was blocked: "+reason)
+	return nil
+}
+
+// recordModerationAudit records a block in the link's workspace. The IP and
+// user agent are left out: they belong to a reporter or an admin, not to
+// someone the owner should see.
+func recordModerationAudit(actorID *int, action string, url models.URL, before map[string]interface{}) {
+	workspaceID := url.WorkspaceID
+	event := models.AuditEvent{
+		ActorID:     actorID,
+		WorkspaceID: &workspaceID,
+		Action:      action,
+		TargetType:  "url",
+		TargetID:    url.ID,
+		Changes:     models.DiffAudit(before, auditURLFields(url)),
+	}
+	if err := models.RecordAuditEvent(&event); err != nil {
+		fmt.Printf("Failed to record audit event %s: %v\n", event.Action, err)
+	}
+}
+
+func notifyOwner(url models.URL, event, message string) {
+	err := notify.Default.Notify(notify.Notification{
+		Event:   event,
+		Link:    url,
+		Message: message,
+		Data: map[string]interface{}{
+			"blocked":        url.Blocked,
+			"blocked_reason": url.BlockedReason,
+		},
+	})
+	if err != nil {
+		fmt.Printf("Failed to notify about link %d: %v\n", url.ID, err)
+	}
+}
+
+// requireAdmin loads the current user and answers 403 unless they are an
+// admin.
+func requireAdmin(c *gin.Context) (int, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return 0, false
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil || !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "Only admins can review reports",
+		})
+		return 0, false
+	}
+	return user.ID, true
+}
+
+// GetReportQueue lists the reported links, the most reported first, with
+// their reports.
+func GetReportQueue(c *gin.Context) {
+	if _, ok := requireAdmin(c); !ok {
+		return
+	}
+
+	status := c.DefaultQuery("status", models.ReportPending)
+	if status != models.ReportPending && status != models.ReportDismissed && status != models.ReportActioned {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "status must be pending, dismissed or actioned",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	var entries []struct {
+		URLID          int
+		Reports        int64
+		Reporters      int64
+		LastReportedAt string
+	}
+	models.DB.Model(&models.Report{}).
+		Select("url_id, COUNT(*) AS reports, COUNT(DISTINCT reporter_hash) AS reporters, MAX(created_at) AS last_reported_at").
+		Where("status = ?", status).
+		Group("url_id").
+		Order("reporters DESC, last_reported_at DESC, url_id").
+		Offset((page - 1) * limit).Limit(limit).
+		Scan(&entries)
+
+	urlIDs := make([]int, len(entries))
+	for i, entry := range entries {
+		urlIDs[i] = entry.URLID
+	}
+
+	// Links in the trash can still be reported on
+	var urls []models.URL
+	models.DB.Unscoped().Where("id IN ?", urlIDs).Find(&urls)
+	urlsByID := make(map[int]models.URL, len(urls))
+	for _, url := range urls {
+		urlsByID[url.ID] = url
+	}
+
+	var reports []models.Report
+	models.DB.Where("url_id IN ? AND status = ?", urlIDs, status).Order("created_at DESC, id DESC").Find(&reports)
+	reportsByURL := map[int][]models.Report{}
+	for _, report := range reports {
+		reportsByURL[report.URLID] = append(reportsByURL[report.URLID], report)
+	}
+
+	queue := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		url := urlsByID[entry.URLID]
+		reasons := map[string]int{}
+		for _, report := range reportsByURL[entry.URLID] {
+			reasons[report.Reason]++
+		}
+		queue = append(queue, gin.H{
+			"url": gin.H{
+				"id":             url.ID,
+				"original_url":   url.OriginalURL,
+				"short_code":     url.ShortCode,
+				"title":          url.Title,
+				"user_id":        url.UserID,
+				"workspace_id":   url.WorkspaceID,
+				"disabled":       url.Disabled,
+				"blocked":        url.Blocked,
+				"blocked_reason": url.BlockedReason,
+				"blocked_at":     url.BlockedAt,
+				"deleted_at":     url.DeletedAt,
+				"created_at":     url.CreatedAt,
+			},
+			"reports_count":   entry.Reports,
+			"reporters_count": entry.Reporters,
+			"reasons":         reasons,
+			"reports":         reportsByURL[entry.URLID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Reports retrieved successfully",
+		"data": gin.H{
+			"queue":  queue,
+			"page":   page,
+			"limit":  limit,
+			"status": status,
+		},
+	})
+}
+
+// findModeratedURL loads the link of an admin action, trashed links included.
+func findModeratedURL(c *gin.Context) (models.URL, bool) {
+	var url models.URL
+	if err := models.DB.Unscoped().First(&url, paramID(c, "id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URL not found",
+		})
+		return url, false
+	}
+	return url, true
+}
+
+// BlockURL blocks a link after review and closes its pending reports.
+func BlockURL(c *gin.Context) {
+	adminID, ok := requireAdmin(c)
+	if !ok {
+		return
+	}
+	url, ok := findModeratedURL(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason" binding:"max=500"`
+	}
+	// The body is optional
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if input.Reason == "" {
+		input.Reason = "Blocked after review of abuse reports"
+	}
+
+	if err := blockLink(&url, input.Reason, &adminID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to block URL",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "URL blocked successfully",
+		"data":    url,
+	})
+}
+
+// UnblockURL lifts the block of a link and dismisses its pending reports.
+// For a link that isn't blocked it only dismisses the reports.
+func UnblockURL(c *gin.Context) {
+	adminID, ok := requireAdmin(c)
+	if !ok {
+		return
+	}
+	url, ok := findModeratedURL(c)
+	if !ok {
+		return
+	}
+
+	before := auditURLFields(url)
+	wasBlocked := url.Blocked
+	url.Blocked = false
+	url.BlockedReason = ""
+	url.BlockedAt = nil
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.URL{}).Where("id = ?", url.ID).UpdateColumns(map[string]interface{}{
+			"blocked":        false,
+			"blocked_reason": "",
+			"blocked_at":     nil,
+		}).Error; err != nil {
+			return err
+		}
+		return models.ReviewReports(tx, url.ID, models.ReportDismissed, adminID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to unblock URL",
+		})
+		return
+	}
+
+	if wasBlocked {
+		recordModerationAudit(&adminID, models.AuditURLUnblocked, url, before)
+		notifyOwner(url, models.EventLinkUnblocked, "Your link was reviewed and unblocked")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "URL unblocked successfully",
+		"data":    url,
+	})
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errShortCodeTaken = errors.New("short code taken")
+
+// GetURLRevisions lists the versions of a link's destination and short code,
+// newest first. The first entry is the current state.
+func GetURLRevisions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var url models.URL
+	if err := models.DB.First(&url, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URL not found",
+		})
+		return
+	}
+
+	if !authorizeURL(c, userID, url, models.RoleViewer) {
+		return
+	}
+
+	var revisions []models.URLRevision
+	if err := models.DB.Where("url_id = ?", url.ID).Order("version DESC").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve revisions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Revisions retrieved successfully",
+		"data":    revisions,
+	})
+}
+
+// RollbackURL restores the destination and short code of an earlier revision.
+// The rollback is recorded as a new revision, so it can be undone as well.
+func RollbackURL(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var url models.URL
+	if err := models.DB.Preload("Tags").First(&url, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URL not found",
+		})
+		return
+	}
+
+	if !authorizeURL(c, userID, url, models.RoleEditor) {
+		return
+	}
+
+	var target models.URLRevision
+	if err := models.DB.Where("url_id = ? AND version = ?", url.ID, c.Param("version")).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Revision not found",
+		})
+		return
+	}
+
+	if target.OriginalURL == url.OriginalURL && target.ShortCode == url.ShortCode {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "The link already matches this revision",
+		})
+		return
+	}
+
+	before := url
+	previous := linkEventData(url)
+	if target.OriginalURL != url.OriginalURL {
+		url.ResetHealth()
+	}
+	url.OriginalURL = target.OriginalURL
+	url.Domain = models.DomainOf(target.OriginalURL)
+	url.ShortCode = target.ShortCode
+	if _, ok := followRedirects(c, &url, false); !ok {
+		return
+	}
+
+	var revision models.URLRevision
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		// The old short code may have been given to another link since
+		var taken int64
+		tx.Unscoped().Model(&models.URL{}).Where("short_code = ? AND id <> ?", url.ShortCode, url.ID).Count(&taken)
+		if taken > 0 {
+			return errShortCodeTaken
+		}
+
+		if err := tx.Model(&models.URL{}).Where("id = ?", url.ID).Updates(map[string]interface{}{
+			"original_url":       url.OriginalURL,
+			"domain":             url.Domain,
+			"short_code":         url.ShortCode,
+			"health_status":      url.HealthStatus,
+			"health_failures":    url.HealthFailures,
+			"health_status_code": url.HealthStatusCode,
+			"health_checked_at":  url.HealthCheckedAt,
+			"final_url":          url.FinalURL,
+			"redirect_hops":      url.RedirectHops,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		revision, err = models.RecordURLRevision(tx, url, int(userID), &target.Version)
+		return err
+	})
+	if errors.Is(err, errShortCodeTaken) {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  false,
+			"message": "The short code of this revision is now used by another link",
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to roll back URL",
+		})
+		return
+	}
+
+	actor, workspaceID := int(userID), url.WorkspaceID
+	recordAudit(c, models.AuditEvent{
+		ActorID:     &actor,
+		WorkspaceID: &workspaceID,
+		Action:      models.AuditURLUpdated,
+		TargetType:  "url",
+		TargetID:    url.ID,
+		Changes:     models.DiffAudit(auditURLFields(before), auditURLFields(url)),
+		Details:     fmt.Sprintf("rolled back to revision %d", target.Version),
+	})
+
+	data := linkEventData(url)
+	data["previous"] = previous
+	notifyWebhooks(url.WorkspaceID, models.EventLinkUpdated, data)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "URL rolled back successfully",
+		"data": gin.H{
+			"id":           url.ID,
+			"original_url": url.OriginalURL,
+			"short_code":   url.ShortCode,
+			"short_url":    "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
+			"revision":     revision,
+		},
+	})
+}
+
+// revisionAnnotations returns the destination changes of the filtered links
+// of a workspace within the analytics range, to mark them on the time series.
+func revisionAnnotations(workspaceID int, filter linkFilter, buckets analyticsBuckets) []gin.H {
+	var rows []struct {
+		URLID             int
+		ShortCode         string
+		Version           int
+		OriginalURL       string
+		PreviousURL       string
+		PreviousShortCode string
+		EditedBy          int
+		CreatedAt         time.Time
+	}
+	filter.apply(models.DB.Table("url_revisions AS rev").
+		Select("rev.url_id, rev.short_code, rev.version, rev.original_url, prev.original_url AS previous_url, prev.short_code AS previous_short_code, rev.edited_by, rev.created_at").
+		Joins("JOIN urls ON urls.id = rev.url_id").
+		Joins("JOIN url_revisions AS prev ON prev.url_id = rev.url_id AND prev.version = rev.version - 1").
+		Where("urls.workspace_id = ? AND urls.deleted_at IS NULL", workspaceID).
+		Where(models.JulianDay("rev.created_at")+" >= ? AND "+models.JulianDay("rev.created_at")+" < ?", models.ToJulianDay(buckets.from), models.ToJulianDay(buckets.to))).
+		Order(models.JulianDay("rev.created_at")).
+		Scan(&rows)
+
+	annotations := make([]gin.H, len(rows))
+	for i, row := range rows {
+		at := row.CreatedAt.In(buckets.loc)
+		annotations[i] = gin.H{
+			"time":                bucketLabel(buckets.granularity, bucketStart(buckets.granularity, at)),
+			"at":                  at,
+			"url_id":              row.URLID,
+			"version":             row.Version,
+			"short_code":          row.ShortCode,
+			"original_url":        row.OriginalURL,
+			"previous_short_code": row.PreviousShortCode,
+			"previous_url":        row.PreviousURL,
+			"edited_by":           row.EditedBy,
+		}
+	}
+	return annotations
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// setupTestDB connects to a fresh database in a temporary directory.
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Chdir(t.TempDir())
+	models.ConnectDB()
+	t.Cleanup(func() {
+		if sqlDB, err := models.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// bearer returns an Authorization header signed in as the user.
+func bearer(t *testing.T, userID int) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString(jwtKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
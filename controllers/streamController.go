@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"backend-go/events"
+	"backend-go/models"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	streamHeartbeatInterval = 15 * time.Second
+	streamTokenTTL          = time.Minute
+)
+
+// CreateStreamToken issues a short-lived token for opening the click stream
+// from a browser: EventSource can't send the Authorization header, so the
+// token goes in the token query parameter instead. It only opens the stream,
+// since query strings end up in logs.
+func CreateStreamToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	expiresAt := time.Now().Add(streamTokenTTL)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"stream_user_id": userID,
+		"exp":            expiresAt.Unix(),
+	}).SignedString(jwtKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Stream token created successfully",
+		"data": gin.H{
+			"token":      token,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// streamUserID reads the user ID from a stream token in the token query
+// parameter, or else from the Authorization header. On failure it writes the
+// error response and returns false.
+func streamUserID(c *gin.Context) (uint, bool) {
+	tokenString := c.Query("token")
+	if tokenString == "" {
+		return currentUserID(c)
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtKey, nil
+	}, jwt.WithExpirationRequired())
+
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return 0, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		return 0, false
+	}
+
+	// Login tokens have user_id instead and don't work here
+	userIDClaim, ok := claims["stream_user_id"].(float64)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		return 0, false
+	}
+
+	return uint(userIDClaim), true
+}
+
+// StreamClicks pushes the workspace's click events over Server-Sent Events as
+// RedirectURL records them. Reconnecting clients send Last-Event-ID and get
+// the events they missed, as long as they are still in the broker history.
+func StreamClicks(c *gin.Context) {
+	userID, ok := streamUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	shortCode := c.Query("short_code")
+	if shortCode != "" {
+		var url models.URL
+		if err := models.DB.Where("short_code = ? AND workspace_id = ?", shortCode, membership.WorkspaceID).First(&url).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  false,
+				"message": "Short URL not found",
+			})
+			return
+		}
+	}
+
+	lastEventID, _ := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	if lastEventID == 0 {
+		// EventSource can't set headers on the first connection
+		lastEventID, _ = strconv.ParseUint(c.Query("last_event_id"), 10, 64)
+	}
+
+	sub, replay := events.Clicks.Subscribe(membership.WorkspaceID, shortCode, lastEventID)
+	defer events.Clicks.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering
+
+	for _, evt := range replay {
+		c.Render(-1, clickSSE(evt))
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case evt, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and resumes
+				return false
+			}
+			c.Render(-1, clickSSE(evt))
+			return true
+		case <-heartbeat.C:
+			// Comment line, ignored by EventSource but keeps proxies from timing out
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		}
+	})
+}
+
+func clickSSE(evt events.ClickEvent) sse.Event {
+	return sse.Event{
+		Id:    strconv.FormatUint(evt.ID, 10),
+		Event: "click",
+		Data:  evt,
+	}
+}
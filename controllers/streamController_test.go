@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"backend-go/events"
+	"backend-go/middlewares"
+	"backend-go/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestStreamToken(t *testing.T) {
+	setupTestDB(t)
+	models.CreateWorkspace(models.DB, &models.Workspace{Name: "Personal", CreatedBy: 1, Personal: true})
+
+	r := gin.New()
+	r.POST("/api/stream/token", CreateStreamToken)
+	r.GET("/api/stream/clicks", StreamClicks)
+	r.GET("/api/profile", middlewares.AuthMiddleware(), GetProfile)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/stream/token", nil)
+	req.Header.Set("Authorization", bearer(t, 1))
+	r.ServeHTTP(w, req)
+	var created struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusOK || created.Data.Token == "" {
+		t.Fatalf("POST /api/stream/token = %d: %s", w.Code, w.Body)
+	}
+
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"stream_user_id": 1,
+		"exp":            time.Now().Add(-time.Minute).Unix(),
+	}).SignedString(jwtKey)
+	noExpiry, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"stream_user_id": 1}).SignedString(jwtKey)
+
+	t.Run("rejected", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			path          string
+			authorization string
+		}{
+			{"no token", "/api/stream/clicks", ""},
+			{"expired stream token", "/api/stream/clicks?token=" + expired, ""},
+			{"stream token without expiry", "/api/stream/clicks?token=" + noExpiry, ""},
+			{"login token in the query", "/api/stream/clicks?token=" + strings.TrimPrefix(bearer(t, 1), "Bearer "), ""},
+			{"stream token as a login token", "/api/profile", "Bearer " + created.Data.Token},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s: status = %d, want %d", tt.name, w.Code, http.StatusUnauthorized)
+			}
+		}
+	})
+
+	t.Run("opens the stream", func(t *testing.T) {
+		workspaceID, _ := models.PersonalWorkspaceID(1)
+		seen := events.Clicks.Publish(events.ClickEvent{WorkspaceID: workspaceID, ShortCode: "seen"})
+		events.Clicks.Publish(events.ClickEvent{WorkspaceID: workspaceID, ShortCode: "missed"})
+
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/stream/clicks?token="+created.Data.Token+"&last_event_id="+strconv.FormatUint(seen.ID, 10), nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d", resp.StatusCode)
+		}
+
+		// The missed event is replayed first
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "data:") {
+				if !strings.Contains(scanner.Text(), `"short_code":"missed"`) {
+					t.Errorf("first event = %s", scanner.Text())
+				}
+				return
+			}
+		}
+		t.Fatalf("stream ended without events: %v", scanner.Err())
+	})
+}
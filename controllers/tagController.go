@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TagInput struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color"`
+}
+
+func CreateTag(c *gin.Context) {
+	var input TagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	tag := models.Tag{
+		UserID:      int(userID),
+		WorkspaceID: membership.WorkspaceID,
+		Name:        strings.TrimSpace(input.Name),
+		Color:       input.Color,
+	}
+
+	var existing models.Tag
+	if err := models.DB.Where("workspace_id = ? AND name = ?", membership.WorkspaceID, tag.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  false,
+			"message": "Tag already exists",
+		})
+		return
+	}
+
+	if err := models.DB.Create(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to create tag",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Tag created successfully",
+		"data":    tag,
+	})
+}
+
+func GetTags(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var tags []struct {
+		models.Tag
+		LinkCount int64 `json:"link_count"`
+	}
+	if err := models.DB.Model(&models.Tag{}).
+		Select("tags.*, (SELECT COUNT(*) FROM url_tags JOIN urls ON urls.id = url_tags.url_id WHERE url_tags.tag_id = tags.id AND urls.deleted_at IS NULL) AS link_count").
+		Where("workspace_id = ?", membership.WorkspaceID).
+		Order("name").
+		Scan(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve tags",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Tags retrieved successfully",
+		"data":    tags,
+	})
+}
+
+func UpdateTag(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var tag models.Tag
+	if err := models.DB.Where("id = ? AND workspace_id = ?", c.Param("id"), membership.WorkspaceID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Tag not found",
+		})
+		return
+	}
+
+	var input TagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name != tag.Name {
+		var existing models.Tag
+		if err := models.DB.Where("workspace_id = ? AND name = ?", membership.WorkspaceID, name).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  false,
+				"message": "Tag already exists",
+			})
+			return
+		}
+	}
+
+	tag.Name = name
+	tag.Color = input.Color
+
+	if err := models.DB.Save(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update tag",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Tag updated successfully",
+		"data":    tag,
+	})
+}
+
+func DeleteTag(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var tag models.Tag
+	if err := models.DB.Where("id = ? AND workspace_id = ?", c.Param("id"), membership.WorkspaceID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Tag not found",
+		})
+		return
+	}
+
+	// Untag the links first, the links themselves stay
+	models.DB.Exec("DELETE FROM url_tags WHERE tag_id = ?", tag.ID)
+
+	if err := models.DB.Delete(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to delete tag",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Tag deleted successfully",
+	})
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
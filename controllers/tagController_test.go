@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBulkUpdateTags(t *testing.T) {
+	setupTestDB(t)
+	models.CreateWorkspace(models.DB, &models.Workspace{Name: "Personal", CreatedBy: 1, Personal: true})
+	tag := models.Tag{UserID: 1, WorkspaceID: 1, Name: "sale"}
+	models.DB.Create(&tag)
+	link := models.URL{ShortCode: "tagged", OriginalURL: "https://example.com", UserID: 1, WorkspaceID: 1, Tags: []models.Tag{tag}}
+	models.DB.Create(&link)
+
+	r := gin.New()
+	r.POST("/api/urls/bulk", BulkUpdateURLs)
+	ids := `"ids":[` + strconv.Itoa(link.ID) + `]`
+
+	steps := []struct {
+		name     string
+		body     string
+		wantTags []string // On the link
+		wantAll  []string // In the workspace
+	}{
+		{"removing a missing tag creates nothing", `{` + ids + `,"action":"remove_tags","tags":["typo"]}`, []string{"sale"}, []string{"sale"}},
+		{"adding creates the tag", `{` + ids + `,"action":"add_tags","tags":["new","sale"]}`, []string{"new", "sale"}, []string{"new", "sale"}},
+		{"removing keeps the tag", `{` + ids + `,"action":"remove_tags","tags":["sale","typo"]}`, []string{"new"}, []string{"new", "sale"}},
+	}
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/urls/bulk", strings.NewReader(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", bearer(t, 1))
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", step.name, w.Code, w.Body)
+		}
+
+		var onLink, all []string
+		models.DB.Table("tags").Joins("JOIN url_tags ON url_tags.tag_id = tags.id").Where("url_tags.url_id = ?", link.ID).Order("name").Pluck("name", &onLink)
+		models.DB.Model(&models.Tag{}).Where("workspace_id = ?", 1).Order("name").Pluck("name", &all)
+		if !slices.Equal(onLink, step.wantTags) || !slices.Equal(all, step.wantAll) {
+			t.Errorf("%s: link tags %v, workspace tags %v, want %v and %v", step.name, onLink, all, step.wantTags, step.wantAll)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransferInput struct {
+	URLIDs        []int  `json:"url_ids" binding:"required,min=1"`
+	ToUser        string `json:"to_user"` // Username or email of the receiving user
+	ToWorkspaceID *int   `json:"to_workspace_id"`
+	Note          string `json:"note"`
+}
+
+// CreateTransfer asks a user or a workspace to take over links of the
+// request's workspace. Nothing moves until the receiving side accepts.
+func CreateTransfer(c *gin.Context) {
+	var input TransferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if (input.ToUser == "") == (input.ToWorkspaceID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Provide either to_user or to_workspace_id",
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	transfer := models.LinkTransfer{
+		FromWorkspaceID: membership.WorkspaceID,
+		Note:            strings.TrimSpace(input.Note),
+		Status:          models.TransferPending,
+		RequestedBy:     int(userID),
+	}
+
+	if input.ToWorkspaceID != nil {
+		var workspace models.Workspace
+		if err := models.DB.First(&workspace, *input.ToWorkspaceID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  false,
+				"message": "Workspace not found",
+			})
+			return
+		}
+		if workspace.ID == membership.WorkspaceID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": "Links are already in this workspace",
+			})
+			return
+		}
+		transfer.ToWorkspaceID = &workspace.ID
+	} else {
+		var recipient models.User
+		if err := models.DB.Where("username = ? OR email = ?", input.ToUser, strings.ToLower(input.ToUser)).First(&recipient).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  false,
+				"message": "User not found",
+			})
+			return
+		}
+		transfer.ToUserID = &recipient.ID
+	}
+
+	// Editors can hand links to other members, only admins can give them away
+	if models.TransferLeavesWorkspace(models.DB, transfer) && !models.RoleAtLeast(membership.Role, models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "Only workspace admins can move links out of the workspace",
+		})
+		return
+	}
+
+	var urls []models.URL
+	models.DB.Where("id IN ? AND workspace_id = ?", input.URLIDs, membership.WorkspaceID).Find(&urls)
+	if len(urls) != len(uniqueIDs(input.URLIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URLs not found",
+		})
+		return
+	}
+
+	// A link can only be part of one pending transfer at a time
+	var pending int64
+	models.DB.Model(&models.LinkTransferItem{}).
+		Joins("JOIN link_transfers ON link_transfers.id = link_transfer_items.transfer_id").
+		Where("link_transfers.status = ? AND link_transfer_items.url_id IN ?", models.TransferPending, input.URLIDs).
+		Count(&pending)
+	if pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  false,
+			"message": "Some links already have a pending transfer",
+		})
+		return
+	}
+
+	for _, url := range urls {
+		transfer.Items = append(transfer.Items, models.LinkTransferItem{
+			URLID:               url.ID,
+			PreviousUserID:      url.UserID,
+			PreviousWorkspaceID: url.WorkspaceID,
+		})
+	}
+
+	if err := models.DB.Create(&transfer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to create transfer",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Transfer requested successfully",
+		"data":    transfer,
+	})
+}
+
+// GetTransfers lists the transfers the user can accept (direction=incoming,
+// the default) or has requested (direction=outgoing), optionally by status.
+func GetTransfers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query := models.DB.Preload("Items")
+	switch c.DefaultQuery("direction", "incoming") {
+	case "incoming":
+		query = query.Where("to_user_id = ? OR to_workspace_id IN (SELECT workspace_id FROM memberships WHERE user_id = ? AND role IN ?)",
+			userID, userID, []string{models.RoleOwner, models.RoleAdmin})
+	case "outgoing":
+		query = query.Where("requested_by = ?", userID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "direction must be incoming or outgoing",
+		})
+		return
+	}
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var transfers []models.LinkTransfer
+	if err := query.Order("created_at DESC").Find(&transfers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve transfers",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Transfers retrieved successfully",
+		"data":    transfers,
+	})
+}
+
+func AcceptTransfer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	transfer, ok := findIncomingTransfer(c, userID)
+	if !ok {
+		return
+	}
+
+	err := models.AcceptLinkTransfer(&transfer, int(userID))
+	if errors.Is(err, models.ErrTransferNotAllowed) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "The links would leave their workspace, but the requester is no longer one of its admins",
+		})
+		return
+	} else if errors.Is(err, models.ErrTransferLinksChanged) {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  false,
+			"message": "Some links were moved or deleted since the transfer was requested",
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to accept transfer",
+		})
+		return
+	}
+
+	// Log the move in both the old and the new workspace
+	for _, item := range transfer.Items {
+		before := map[string]interface{}{"user_id": item.PreviousUserID, "workspace_id": item.PreviousWorkspaceID}
+		after := map[string]interface{}{"user_id": *item.NewUserID, "workspace_id": *item.NewWorkspaceID}
+		workspaceIDs := []int{item.PreviousWorkspaceID}
+		if *item.NewWorkspaceID != item.PreviousWorkspaceID {
+			workspaceIDs = append(workspaceIDs, *item.NewWorkspaceID)
+		}
+		for _, workspaceID := range workspaceIDs {
+			actor, workspace := int(userID), workspaceID
+			recordAudit(c, models.AuditEvent{
+				ActorID:     &actor,
+				WorkspaceID: &workspace,
+				Action:      models.AuditURLTransferred,
+				TargetType:  "url",
+				TargetID:    item.URLID,
+				Changes:     models.DiffAudit(before, after),
+				Details:     fmt.Sprintf("transfer %d requested by user %d", transfer.ID, transfer.RequestedBy),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Transfer accepted successfully",
+		"data":    transfer,
+	})
+}
+
+func DeclineTransfer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	transfer, ok := findIncomingTransfer(c, userID)
+	if !ok {
+		return
+	}
+
+	closeTransfer(c, &transfer, models.TransferDeclined, models.AuditTransferDeclined, userID)
+}
+
+// CancelTransfer withdraws a pending transfer. Only the requester can.
+func CancelTransfer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var transfer models.LinkTransfer
+	if err := models.DB.Preload("Items").Where("id = ? AND requested_by = ? AND status = ?", c.Param("id"), userID, models.TransferPending).First(&transfer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Transfer not found",
+		})
+		return
+	}
+
+	closeTransfer(c, &transfer, models.TransferCancelled, models.AuditTransferCancelled, userID)
+}
+
+// closeTransfer ends a pending transfer without moving its links and logs it
+// in the workspace the links would have left.
+func closeTransfer(c *gin.Context, transfer *models.LinkTransfer, status, action string, userID uint) {
+	now := time.Now()
+	respondedBy := int(userID)
+	transfer.Status = status
+	transfer.RespondedBy = &respondedBy
+	transfer.RespondedAt = &now
+
+	if err := models.DB.Omit("Items").Save(transfer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update transfer",
+		})
+		return
+	}
+
+	actor, workspace := int(userID), transfer.FromWorkspaceID
+	recordAudit(c, models.AuditEvent{
+		ActorID:     &actor,
+		WorkspaceID: &workspace,
+		Action:      action,
+		TargetType:  "transfer",
+		TargetID:    transfer.ID,
+		Changes:     models.DiffAudit(map[string]interface{}{"status": models.TransferPending}, map[string]interface{}{"status": status}),
+		Details:     fmt.Sprintf("transfer %d requested by user %d", transfer.ID, transfer.RequestedBy),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Transfer " + status,
+		"data":    transfer,
+	})
+}
+
+// findIncomingTransfer loads a pending transfer addressed to the user, or to a
+// workspace the user administers.
+func findIncomingTransfer(c *gin.Context, userID uint) (models.LinkTransfer, bool) {
+	var transfer models.LinkTransfer
+	if err := models.DB.Preload("Items").Where("id = ? AND status = ?", c.Param("id"), models.TransferPending).First(&transfer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Transfer not found",
+		})
+		return transfer, false
+	}
+
+	if transfer.ToWorkspaceID != nil {
+		membership, err := models.FindMembership(*transfer.ToWorkspaceID, int(userID))
+		if err == nil && models.RoleAtLeast(membership.Role, models.RoleAdmin) {
+			return transfer, true
+		}
+	} else if *transfer.ToUserID == int(userID) {
+		return transfer, true
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{
+		"status":  false,
+		"message": "Transfer not found",
+	})
+	return transfer, false
+}
+
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
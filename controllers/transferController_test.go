@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCloseTransferAudit(t *testing.T) {
+	setupTestDB(t)
+	const requester, recipient = 1, 2
+	models.DB.Create(&[]models.User{
+		{Username: "requester", Email: "requester@example.com"},
+		{Username: "recipient", Email: "recipient@example.com"},
+	})
+	models.DB.Create(&models.Workspace{Name: "Team", CreatedBy: requester})
+	models.DB.Create(&models.Membership{WorkspaceID: 1, UserID: requester, Role: models.RoleOwner})
+	to := recipient
+	models.DB.Create(&[]models.LinkTransfer{
+		{FromWorkspaceID: 1, ToUserID: &to, RequestedBy: requester},
+		{FromWorkspaceID: 1, ToUserID: &to, RequestedBy: requester},
+	})
+
+	r := gin.New()
+	r.POST("/api/transfers/:id/decline", DeclineTransfer)
+	r.POST("/api/transfers/:id/cancel", CancelTransfer)
+	for _, step := range []struct {
+		as   int
+		path string
+	}{
+		{recipient, "/api/transfers/1/decline"},
+		{requester, "/api/transfers/2/cancel"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, step.path, nil)
+		req.Header.Set("Authorization", bearer(t, step.as))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, body %s", step.path, w.Code, w.Body)
+		}
+	}
+
+	var events []models.AuditEvent
+	models.DB.Order("id").Find(&events)
+	want := []struct {
+		actor, target int
+		action, to    string
+	}{
+		{recipient, 1, models.AuditTransferDeclined, models.TransferDeclined},
+		{requester, 2, models.AuditTransferCancelled, models.TransferCancelled},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d audit events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		w := want[i]
+		if event.Action != w.action || *event.ActorID != w.actor || *event.WorkspaceID != 1 ||
+			event.TargetType != "transfer" || event.TargetID != w.target || event.Changes["status"].To != w.to {
+			t.Errorf("event %d = %+v, want %s by user %d on transfer %d in workspace 1", i, event, w.action, w.actor, w.target)
+		}
+	}
+}
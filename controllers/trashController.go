@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTrash lists the deleted links of the workspace, most recently deleted
+// first, with the time each one will be purged.
+func GetTrash(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var workspace models.Workspace
+	if err := models.DB.First(&workspace, membership.WorkspaceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Workspace not found",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	sort := keysetSort{
+		Name:     "deleted_at",
+		Expr:     "urls.deleted_at",
+		Time:     true,
+		IDColumn: "urls.id",
+		Order:    "desc",
+	}
+
+	cursor, err := decodeCursor(c.Query("cursor"), sort.Name, sort.Order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Invalid cursor",
+		})
+		return
+	}
+
+	query := models.DB.Unscoped().Model(&models.URL{}).
+		Where("urls.workspace_id = ? AND urls.deleted_at IS NOT NULL", membership.WorkspaceID)
+
+	ids, nextCursor, prevCursor, err := keysetPage(query, sort, cursor, limit)
+	var urls []models.URL
+	if err == nil && len(ids) > 0 {
+		err = models.DB.Unscoped().Preload("Tags").Where("id IN ?", ids).Order("deleted_at DESC, id DESC").Find(&urls).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve trash",
+		})
+		return
+	}
+
+	clickCounts := urlClickCounts(ids)
+	links := make([]gin.H, len(urls))
+	for i, url := range urls {
+		links[i] = gin.H{
+			"id":           url.ID,
+			"original_url": url.OriginalURL,
+			"short_code":   url.ShortCode,
+			"title":        url.Title,
+			"click_count":  clickCounts[url.ID],
+			"tags":         tagNames(url.Tags),
+			"folder_id":    url.FolderID,
+			"user_id":      url.UserID,
+			"created_at":   url.CreatedAt,
+			"deleted_at":   url.DeletedAt.Time,
+			"purge_at":     url.PurgeAt(workspace.TrashRetentionDays),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Trash retrieved successfully",
+		"data": gin.H{
+			"urls":                 links,
+			"trash_retention_days": workspace.TrashRetentionDays,
+			"pagination": gin.H{
+				"per_page":    limit,
+				"next_cursor": nextCursor,
+				"prev_cursor": prevCursor,
+				"has_next":    nextCursor != "",
+				"has_prev":    prevCursor != "",
+			},
+		},
+	})
+}
+
+// RestoreURL takes a link out of the trash. Its short code was kept reserved,
+// so it redirects again right away.
+func RestoreURL(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	url, ok := findTrashedURL(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	if err := models.DB.Unscoped().Model(&url).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to restore URL",
+		})
+		return
+	}
+	url.DeletedAt = gorm.DeletedAt{}
+
+	recordURLAudit(c, userID, models.AuditURLRestored, url, nil, auditURLFields(url))
+	notifyWebhooks(url.WorkspaceID, models.EventLinkRestored, linkEventData(url))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "URL restored successfully",
+		"data": gin.H{
+			"id":           url.ID,
+			"original_url": url.OriginalURL,
+			"short_code":   url.ShortCode,
+			"short_url":    "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
+			"title":        url.Title,
+			"tags":         tagNames(url.Tags),
+			"folder_id":    url.FolderID,
+			"workspace_id": url.WorkspaceID,
+		},
+	})
+}
+
+// PurgeURL permanently deletes a link from the trash with its clicks and
+// releases its short code. This can't be undone, so it takes an admin.
+func PurgeURL(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	url, ok := findTrashedURL(c, userID, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	if err := models.DB.Transaction(func(tx *gorm.DB) error {
+		return models.PurgeURL(tx, url.ID)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to purge URL",
+		})
+		return
+	}
+
+	recordURLAudit(c, userID, models.AuditURLPurged, url, auditURLFields(url), nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "URL purged successfully",
+	})
+}
+
+// findTrashedURL loads the link in the trash with the :id route parameter,
+// checking the user's role in its workspace.
+func findTrashedURL(c *gin.Context, userID uint, minRole string) (models.URL, bool) {
+	var url models.URL
+	if err := models.DB.Unscoped().Preload("Tags").Scopes(preloadRouting).Where("id = ? AND deleted_at IS NOT NULL", c.Param("id")).First(&url).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URL not found in the trash",
+		})
+		return url, false
+	}
+
+	return url, authorizeURL(c, userID, url, minRole)
+}
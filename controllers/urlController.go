@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"backend-go/events"
 	"backend-go/models"
+	"backend-go/redirects"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,11 +13,41 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 type CreateURLRequest struct {
-	OriginalURL string `json:"original_url" binding:"required,url"`
-	CustomCode  string `json:"custom_code"`
+	OriginalURL string     `json:"original_url" binding:"required,url"`
+	CustomCode  string     `json:"custom_code"`
+	Title       string     `json:"title"`
+	Notes       string     `json:"notes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Tags        []string   `json:"tags"`
+	FolderID    *int       `json:"folder_id"`
+	ActiveFrom  *time.Time `json:"active_from"`
+	FallbackURL string     `json:"fallback_url" binding:"omitempty,url"`
+	// Destinations by time window and by visitor, first match wins
+	Schedules []ScheduleInput `json:"schedules" binding:"omitempty,dive"`
+	Rules     []RuleInput     `json:"rules" binding:"omitempty,dive"`
+	// A/B split of the original destination
+	Variants         []VariantInput `json:"variants" binding:"omitempty,dive"`
+	TrackConversions bool           `json:"track_conversions"`
+	// Merged into the destination, without replacing its own parameters
+	UTMSource    string `json:"utm_source" binding:"max=255"`
+	UTMMedium    string `json:"utm_medium" binding:"max=255"`
+	UTMCampaign  string `json:"utm_campaign" binding:"max=255"`
+	UTMTerm      string `json:"utm_term" binding:"max=255"`
+	UTMContent   string `json:"utm_content" binding:"max=255"`
+	ForwardQuery bool   `json:"forward_query"`
+	// Match longer paths too; the destination may use {path}, {query} and
+	// the names in path_params
+	MatchPrefix bool     `json:"match_prefix"`
+	PathParams  []string `json:"path_params"`
+	// Deep links into the mobile apps
+	IOS     models.AppLink `json:"ios"`
+	Android models.AppLink `json:"android"`
+	// Replace a destination that redirects with where it ends up
+	Unwrap bool `json:"unwrap"`
 }
 
 func CreateShortURL(c *gin.Context) {
@@ -57,13 +90,19 @@ func CreateShortURL(c *gin.Context) {
 
 	userID := uint(claims["user_id"].(float64))
 
+	// Links are created in the workspace of the request
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
 	var shortCode string
 
 	// Use custom code if provided, otherwise generate random
 	if req.CustomCode != "" {
-		// Check if custom code already exists
+		// Check if custom code already exists, links in the trash included
 		var existingURL models.URL
-		if err := models.DB.Where("short_code = ?", req.CustomCode).First(&existingURL).Error; err == nil {
+		if err := models.DB.Unscoped().Where("short_code = ?", req.CustomCode).First(&existingURL).Error; err == nil {
 			c.JSON(http.StatusConflict, gin.H{
 				"status":  false,
 				"message": "Custom short code already exists",
@@ -75,13 +114,105 @@ func CreateShortURL(c *gin.Context) {
 		shortCode = models.GenerateShortCode()
 	}
 
+	if req.FolderID != nil && !workspaceHasFolder(membership.WorkspaceID, *req.FolderID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Folder not found",
+		})
+		return
+	}
+
+	if req.PathParams == nil {
+		req.PathParams = []string{}
+	}
+	if err := validateLink(req.OriginalURL, req.PathParams, req.IOS, req.Android); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	schedules, err := buildSchedules(req.Schedules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rules, err := buildRules(req.Rules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+	for i := range rules {
+		rules[i].ID = 0
+	}
+
+	variants, err := buildVariants(req.Variants)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+	for i := range variants {
+		variants[i].ID = 0
+	}
+
 	url := models.URL{
-		OriginalURL: req.OriginalURL,
-		ShortCode:   shortCode,
-		UserID:      int(userID),
+		OriginalURL:      req.OriginalURL,
+		ShortCode:        shortCode,
+		Title:            req.Title,
+		Notes:            req.Notes,
+		Domain:           models.DomainOf(req.OriginalURL),
+		ExpiresAt:        localTime(req.ExpiresAt),
+		ActiveFrom:       localTime(req.ActiveFrom),
+		FallbackURL:      req.FallbackURL,
+		TrackConversions: req.TrackConversions,
+		UTMSource:        req.UTMSource,
+		UTMMedium:        req.UTMMedium,
+		UTMCampaign:      req.UTMCampaign,
+		UTMTerm:          req.UTMTerm,
+		UTMContent:       req.UTMContent,
+		ForwardQuery:     req.ForwardQuery,
+		MatchPrefix:      req.MatchPrefix,
+		PathParams:       req.PathParams,
+		IOS:              req.IOS,
+		Android:          req.Android,
+		UserID:           int(userID),
+		WorkspaceID:      membership.WorkspaceID,
+		FolderID:         req.FolderID,
+		Schedules:        schedules,
+		Rules:            rules,
+		Variants:         variants,
+	}
+
+	chain, ok := followRedirects(c, &url, req.Unwrap)
+	if !ok {
+		return
 	}
 
-	if err := models.DB.Create(&url).Error; err != nil {
+	// The link, its tags and its first revision
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := models.FindOrCreateTags(tx, membership.WorkspaceID, int(userID), req.Tags)
+		if err != nil {
+			return err
+		}
+		url.Tags = tags
+		if err := tx.Create(&url).Error; err != nil {
+			return err
+		}
+		_, err = models.RecordURLRevision(tx, url, int(userID), nil)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  false,
 			"message": "Failed to create short URL",
@@ -90,16 +221,45 @@ func CreateShortURL(c *gin.Context) {
 		return
 	}
 
+	recordURLAudit(c, userID, models.AuditURLCreated, url, nil, auditURLFields(url))
+	notifyWebhooks(url.WorkspaceID, models.EventLinkCreated, linkEventData(url))
+
 	c.JSON(http.StatusCreated, gin.H{
 		"status":  true,
 		"message": "Short URL created successfully",
 		"data": gin.H{
-			"id":           url.ID,
-			"original_url": url.OriginalURL,
-			"short_code":   url.ShortCode,
-			"short_url":    "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
-			"click_count":  url.ClickCount,
-			"created_at":   url.CreatedAt,
+			"id":                url.ID,
+			"original_url":      url.OriginalURL,
+			"short_code":        url.ShortCode,
+			"short_url":         "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
+			"title":             url.Title,
+			"notes":             url.Notes,
+			"click_count":       url.ClickCount,
+			"tags":              tagNames(url.Tags),
+			"folder_id":         url.FolderID,
+			"workspace_id":      url.WorkspaceID,
+			"expires_at":        url.ExpiresAt,
+			"disabled":          url.Disabled,
+			"active_from":       url.ActiveFrom,
+			"fallback_url":      url.FallbackURL,
+			"schedules":         url.Schedules,
+			"rules":             url.Rules,
+			"variants":          url.Variants,
+			"track_conversions": url.TrackConversions,
+			"utm_source":        url.UTMSource,
+			"utm_medium":        url.UTMMedium,
+			"utm_campaign":      url.UTMCampaign,
+			"utm_term":          url.UTMTerm,
+			"utm_content":       url.UTMContent,
+			"forward_query":     url.ForwardQuery,
+			"match_prefix":      url.MatchPrefix,
+			"path_params":       url.PathParams,
+			"ios":               url.IOS,
+			"android":           url.Android,
+			"final_url":         url.FinalURL,
+			"redirect_hops":     url.RedirectHops,
+			"redirect_chain":    chain.Hops,
+			"created_at":        url.CreatedAt,
 		},
 	})
 }
@@ -152,96 +312,349 @@ func GetURLs(c *gin.Context) {
 
 	userID := uint(claims["user_id"].(float64))
 
-	// Get total count for pagination info (filtered by user)
-	var totalCount int64
-	models.DB.Model(&models.URL{}).Where("user_id = ?", userID).Count(&totalCount)
+	membership, ok := requireWorkspace(c, userID, models.RoleViewer)
+	if !ok {
+		return
+	}
 
-	// Get URLs with pagination (filtered by user)
-	var urls []models.URL
-	result := models.DB.Where("user_id = ?", userID).Offset(offset).Limit(limitNum).Order("created_at DESC").Find(&urls)
+	// Optional tag / folder filters
+	filter, err := parseLinkFilter(c, membership.WorkspaceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Folder not found",
+		})
+		return
+	}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	// Search, filters and sort order
+	listQuery, err := parseURLListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  false,
-			"message": "Failed to retrieve URLs",
+			"message": err.Error(),
 		})
 		return
 	}
 
-	// Calculate click counts from clicks table for each URL
+	// Get total count for pagination info (filtered by workspace)
+	var totalCount int64
+	listQuery.apply(filter.apply(models.DB.Model(&models.URL{}).Where("urls.workspace_id = ?", membership.WorkspaceID))).Count(&totalCount)
+
+	// Get URLs with pagination (filtered by workspace). Passing cursor, even empty
+	// for the first page, switches from page numbers to cursor pagination.
+	var urls []models.URL
+	var nextCursor, prevCursor string
+	cursorToken, cursorMode := c.GetQuery("cursor")
+
+	if cursorMode {
+		cursor, err := decodeCursor(cursorToken, listQuery.Sort, listQuery.Order)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": "Invalid cursor for this sort order",
+			})
+			return
+		}
+
+		var ids []int
+		ids, nextCursor, prevCursor, err = keysetPage(listQuery.apply(filter.apply(models.DB.Model(&models.URL{}).Where("urls.workspace_id = ?", membership.WorkspaceID))), listQuery.keysetSort(), cursor, limitNum)
+		if err == nil {
+			urls, err = findURLsInOrder(ids)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  false,
+				"message": "Failed to retrieve URLs",
+			})
+			return
+		}
+	} else {
+		result := listQuery.apply(filter.apply(models.DB.Where("urls.workspace_id = ?", membership.WorkspaceID))).Preload("Tags").Scopes(preloadRouting).Offset(offset).Limit(limitNum).Order(listQuery.orderBy()).Find(&urls)
+
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  false,
+				"message": "Failed to retrieve URLs",
+			})
+			return
+		}
+	}
+
+	// Calculate click counts from the rollups for all URLs at once
+	urlIDs := make([]int, len(urls))
 	for i, url := range urls {
-		var clickCount int64
-		models.DB.Table("clicks").Where("url_id = ?", url.ID).Count(&clickCount)
-		urls[i].ClickCount = int(clickCount)
+		urlIDs[i] = url.ID
+	}
+	clickCounts := urlClickCounts(urlIDs)
+	for i, url := range urls {
+		urls[i].ClickCount = int(clickCounts[url.ID])
 	}
 
 	// Calculate pagination info
 	totalPages := (totalCount + int64(limitNum) - 1) / int64(limitNum)
+	pagination := gin.H{
+		"current_page": pageNum,
+		"per_page":     limitNum,
+		"total":        totalCount,
+		"total_pages":  totalPages,
+		"has_next":     pageNum < int(totalPages),
+		"has_prev":     pageNum > 1,
+	}
+	if cursorMode {
+		pagination = gin.H{
+			"per_page":    limitNum,
+			"total":       totalCount,
+			"next_cursor": nextCursor,
+			"prev_cursor": prevCursor,
+			"has_next":    nextCursor != "",
+			"has_prev":    prevCursor != "",
+		}
+	}
 
 	// Build URLs with full short_url
 	urlsWithFullURL := make([]gin.H, len(urls))
 	for i, url := range urls {
 		urlsWithFullURL[i] = gin.H{
-			"id":           url.ID,
-			"original_url": url.OriginalURL,
-			"short_code":   url.ShortCode,
-			"short_url":    "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
-			"click_count":  url.ClickCount,
-			"created_at":   url.CreatedAt,
-			"updated_at":   url.UpdatedAt,
+			"id":                url.ID,
+			"original_url":      url.OriginalURL,
+			"short_code":        url.ShortCode,
+			"short_url":         "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
+			"title":             url.Title,
+			"notes":             url.Notes,
+			"domain":            url.Domain,
+			"click_count":       url.ClickCount,
+			"tags":              tagNames(url.Tags),
+			"folder_id":         url.FolderID,
+			"user_id":           url.UserID,
+			"expires_at":        url.ExpiresAt,
+			"disabled":          url.Disabled,
+			"active_from":       url.ActiveFrom,
+			"fallback_url":      url.FallbackURL,
+			"schedules":         url.Schedules,
+			"rules":             url.Rules,
+			"variants":          url.Variants,
+			"track_conversions": url.TrackConversions,
+			"utm_source":        url.UTMSource,
+			"utm_medium":        url.UTMMedium,
+			"utm_campaign":      url.UTMCampaign,
+			"utm_term":          url.UTMTerm,
+			"utm_content":       url.UTMContent,
+			"forward_query":     url.ForwardQuery,
+			"match_prefix":      url.MatchPrefix,
+			"path_params":       url.PathParams,
+			"ios":               url.IOS,
+			"android":           url.Android,
+			"blocked":           url.Blocked,
+			"blocked_reason":    url.BlockedReason,
+			"health":            linkHealth(url),
+			"final_url":         url.FinalURL,
+			"redirect_hops":     url.RedirectHops,
+			"last_clicked_at":   url.LastClickedAt,
+			"created_at":        url.CreatedAt,
+			"updated_at":        url.UpdatedAt,
 		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
 		"message": "URLs retrieved successfully",
+		"filters": gin.H{
+			"workspace_id": membership.WorkspaceID,
+			"tag":          filter.Tag,
+			"folder":       filter.Folder,
+			"utm_campaign": filter.Campaign,
+			"q":            listQuery.Search,
+			"created_from": formatDate(listQuery.CreatedFrom),
+			"created_to":   formatDate(listQuery.CreatedTo),
+			"min_clicks":   listQuery.MinClicks,
+			"max_clicks":   listQuery.MaxClicks,
+			"status":       listQuery.Status,
+			"health":       listQuery.Health,
+			"domain":       listQuery.Domain,
+			"sort":         listQuery.Sort,
+			"order":        listQuery.Order,
+			"full_text":    models.FTSEnabled,
+		},
 		"data": gin.H{
-			"urls": urlsWithFullURL,
-			"pagination": gin.H{
-				"current_page": pageNum,
-				"per_page":     limitNum,
-				"total":        totalCount,
-				"total_pages":  totalPages,
-				"has_next":     pageNum < int(totalPages),
-				"has_prev":     pageNum > 1,
-			},
+			"urls":       urlsWithFullURL,
+			"pagination": pagination,
 		},
 	})
 }
 
+// validateLink checks the placeholders of a destination template, the names
+// of the path segments they refer to and the deep links of a link.
+func validateLink(destination string, pathParams []string, ios, android models.AppLink) error {
+	if err := models.ValidatePathParams(pathParams); err != nil {
+		return err
+	}
+	if err := models.ValidateTemplate(destination); err != nil {
+		return err
+	}
+	if err := ios.Validate(); err != nil {
+		return fmt.Errorf("ios: %w", err)
+	}
+	if err := android.Validate(); err != nil {
+		return fmt.Errorf("android: %w", err)
+	}
+	return nil
+}
+
+// RedirectURL serves both /:shortCode and the longer paths of prefix links,
+// which are matched by the longest short code.
 func RedirectURL(c *gin.Context) {
-	shortCode := c.Param("shortCode")
+	url, segments, err := models.FindLinkByPath(c.Request.URL.EscapedPath())
+	if err != nil {
+		respondLinkError(c, linkFailure{
+			Status:    http.StatusNotFound,
+			Kind:      models.ErrorPageNotFound,
+			Message:   "Short URL not found",
+			ShortCode: strings.Trim(c.Request.URL.Path, "/"),
+		})
+		return
+	}
 
-	var url models.URL
-	if err := models.DB.Where("short_code = ?", shortCode).First(&url).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "Short URL not found",
+	redirectLink(c, url, segments, nil)
+}
+
+// redirectLink sends the visitor on to the destination of a link and records
+// the click. blockID is the bio page block the visitor came from, if any.
+func redirectLink(c *gin.Context, url models.URL, segments []string, blockID *int) {
+	now := time.Now()
+	if url.Blocked {
+		respondBlocked(c, url)
+		return
+	}
+	if url.Disabled {
+		respondLinkError(c, linkFailure{
+			Status:    http.StatusGone,
+			Kind:      models.ErrorPageDisabled,
+			Message:   "Short URL is disabled",
+			OwnerID:   url.UserID,
+			ShortCode: url.ShortCode,
 		})
 		return
 	}
+	if url.IsExpired(now) {
+		respondLinkError(c, linkFailure{
+			Status:    http.StatusGone,
+			Kind:      models.ErrorPageExpired,
+			Message:   "Short URL has expired",
+			OwnerID:   url.UserID,
+			ShortCode: url.ShortCode,
+		})
+		return
+	}
+
+	// Links that aren't live yet send visitors to their fallback, if any.
+	// Once live, the first matching rule wins over the schedules, and outside
+	// the schedules the A/B split replaces the original URL.
+	loadRouting(&url)
+	visit := newVisit(c)
+	var rule *models.URLRule
+	var variant *models.URLVariant
+	destination := url.Destination(now)
+	if !url.IsActive(now) {
+		if url.FallbackURL == "" {
+			respondLinkError(c, linkFailure{
+				Status:    http.StatusNotFound,
+				Kind:      models.ErrorPageNotFound,
+				Message:   "Short URL is not active yet",
+				OwnerID:   url.UserID,
+				ShortCode: url.ShortCode,
+				Extra:     gin.H{"active_from": url.ActiveFrom},
+			})
+			return
+		}
+		destination = url.FallbackURL
+	} else if rule = url.MatchRule(visit); rule != nil {
+		destination = rule.DestinationURL
+	} else if url.ScheduleAt(now) == nil {
+		if variant = pickVariant(c, url); variant != nil {
+			destination = variant.DestinationURL
+		}
+	}
+
+	// Fill in the template placeholders and forward the rest of the path
+	destination = url.ExpandDestination(destination, models.LinkPath{Segments: segments, Query: c.Request.URL.Query()})
+
+	// Add the link's UTM parameters and, if enabled, the short link's own
+	// query string. Parameters the destination already has win.
+	destination = models.MergeQuery(destination, url.UTMParams())
+	if url.ForwardQuery {
+		destination = models.MergeQuery(destination, c.Request.URL.Query())
+	}
 
 	// Track the click
-	click := models.Click{
-		URLID:     url.ID,
-		ClickedAt: time.Now(),
+	click := newClick(c, url)
+	click.BlockID = blockID
+	if rule != nil {
+		click.RuleID = &rule.ID
+	}
+	if variant != nil {
+		click.VariantID = &variant.ID
 	}
 
-	// Save click record
-	if err := models.DB.Create(&click).Error; err != nil {
+	// Hand the click id to the destination so conversions can be tied back
+	if url.TrackConversions {
+		destination = addQueryParam(destination, "sl_cid", click.CID)
+		c.SetSameSite(http.SameSiteNoneMode)
+		c.SetCookie("sl_cid", click.CID, int(models.AttributionWindow.Seconds()), "/", "", true, true)
+	}
+
+	// Save click record and update the rollups and the click count
+	if err := models.RecordClick(&click); err != nil {
 		// Log error but don't block redirect
 		fmt.Printf("Failed to track click: %v", err)
+	} else {
+		// Notify live dashboards
+		events.Clicks.Publish(events.ClickEvent{
+			UserID:      url.UserID,
+			WorkspaceID: url.WorkspaceID,
+			URLID:       url.ID,
+			ShortCode:   url.ShortCode,
+			ClickedAt:   click.ClickedAt,
+			Country:     click.Country,
+			Referrer:    click.Referrer,
+			Device:      click.Device,
+		})
+
+		data := linkEventData(url)
+		data["click"] = gin.H{
+			"id":         click.ID,
+			"clicked_at": click.ClickedAt,
+			"country":    click.Country,
+			"referrer":   click.Referrer,
+			"device":     click.Device,
+			"rule_id":    click.RuleID,
+			"variant_id": click.VariantID,
+			"block_id":   click.BlockID,
+		}
+		notifyWebhooks(url.WorkspaceID, models.EventLinkClicked, data)
 	}
 
-	// Increment click count in URL table
-	models.DB.Model(&url).Update("click_count", url.ClickCount+1)
+	// Phones with the app get a page that opens it, crawlers the destination
+	if app := url.AppLinkFor(visit.OS); app != nil && visit.Device != "bot" && serveBouncePage(c, *app, destination) {
+		return
+	}
 
-	c.Redirect(http.StatusMovedPermanently, url.OriginalURL)
+	// A permanent redirect would be cached by browsers past the next switch
+	if url.IsDynamic() {
+		c.Redirect(http.StatusFound, destination)
+		return
+	}
+	c.Redirect(http.StatusMovedPermanently, destination)
 }
 
 func GetURLStats(c *gin.Context) {
 	shortCode := c.Param("shortCode")
 
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	var url models.URL
 	if err := models.DB.Where("short_code = ?", shortCode).First(&url).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -251,21 +664,36 @@ func GetURLStats(c *gin.Context) {
 		return
 	}
 
-	// Get actual click count from clicks table
-	var clickCount int64
-	models.DB.Table("clicks").Where("url_id = ?", url.ID).Count(&clickCount)
+	if !authorizeURL(c, userID, url, models.RoleViewer) {
+		return
+	}
+
+	// Get actual click count from the rollups
+	clickCount := urlClickCounts([]int{url.ID})[url.ID]
+	loadRouting(&url)
+
+	var conversions conversionTotal
+	models.DB.Model(&models.Conversion{}).
+		Select("COUNT(*) AS conversions, COUNT(DISTINCT click_id) AS converted_clicks, COALESCE(SUM(revenue), 0) AS revenue").
+		Where("url_id = ?", url.ID).
+		Scan(&conversions)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
 		"message": "URL stats retrieved successfully",
 		"data": gin.H{
-			"id":           url.ID,
-			"original_url": url.OriginalURL,
-			"short_code":   url.ShortCode,
-			"short_url":    "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
-			"click_count":  int(clickCount),
-			"created_at":   url.CreatedAt,
-			"updated_at":   url.UpdatedAt,
+			"id":              url.ID,
+			"original_url":    url.OriginalURL,
+			"short_code":      url.ShortCode,
+			"short_url":       "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
+			"click_count":     int(clickCount),
+			"unique_visitors": urlUniqueVisitors(url.ID),
+			"conversions":     conversions.Conversions,
+			"revenue":         conversions.Revenue,
+			"conversion_rate": rate(conversions.ConvertedClicks, clickCount),
+			"variants":        variantStats(url),
+			"created_at":      url.CreatedAt,
+			"updated_at":      url.UpdatedAt,
 		},
 	})
 }
@@ -273,6 +701,11 @@ func GetURLStats(c *gin.Context) {
 func UpdateURL(c *gin.Context) {
 	id := c.Param("id")
 
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	var url models.URL
 	if err := models.DB.First(&url, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -282,9 +715,40 @@ func UpdateURL(c *gin.Context) {
 		return
 	}
 
+	if !authorizeURL(c, userID, url, models.RoleEditor) {
+		return
+	}
+
 	var input struct {
-		OriginalURL string `json:"original_url" binding:"required"`
-		ShortCode   string `json:"short_code"`
+		OriginalURL     string     `json:"original_url" binding:"required"`
+		ShortCode       string     `json:"short_code"`
+		Title           *string    `json:"title"`
+		Notes           *string    `json:"notes"`
+		ExpiresAt       *time.Time `json:"expires_at"`
+		ClearExpiry     bool       `json:"clear_expiry"`
+		Disabled        *bool      `json:"disabled"`
+		Tags            *[]string  `json:"tags"`      // Replaces the link's tags when set
+		FolderID        *int       `json:"folder_id"` // 0 moves the link back to the root
+		ActiveFrom      *time.Time `json:"active_from"`
+		ClearActiveFrom bool       `json:"clear_active_from"`
+		FallbackURL     string     `json:"fallback_url" binding:"omitempty,url"`
+		ClearFallback   bool       `json:"clear_fallback_url"`
+		// Replace the link's schedules and rules when set
+		Schedules        *[]ScheduleInput `json:"schedules" binding:"omitempty,dive"`
+		Rules            *[]RuleInput     `json:"rules" binding:"omitempty,dive"`
+		Variants         *[]VariantInput  `json:"variants" binding:"omitempty,dive"`
+		TrackConversions *bool            `json:"track_conversions"`
+		UTMSource        *string          `json:"utm_source" binding:"omitempty,max=255"`
+		UTMMedium        *string          `json:"utm_medium" binding:"omitempty,max=255"`
+		UTMCampaign      *string          `json:"utm_campaign" binding:"omitempty,max=255"`
+		UTMTerm          *string          `json:"utm_term" binding:"omitempty,max=255"`
+		UTMContent       *string          `json:"utm_content" binding:"omitempty,max=255"`
+		ForwardQuery     *bool            `json:"forward_query"`
+		MatchPrefix      *bool            `json:"match_prefix"`
+		PathParams       *[]string        `json:"path_params"`
+		IOS              *models.AppLink  `json:"ios"` // Replaces the iOS deep link when set
+		Android          *models.AppLink  `json:"android"`
+		Unwrap           bool             `json:"unwrap"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -295,10 +759,11 @@ func UpdateURL(c *gin.Context) {
 		return
 	}
 
-	// Check if new short_code is already taken by another URL
+	// Check if new short_code is already taken by another URL, links in the
+	// trash included
 	if input.ShortCode != "" && input.ShortCode != url.ShortCode {
 		var existingURL models.URL
-		if err := models.DB.Where("short_code = ?", input.ShortCode).First(&existingURL).Error; err == nil {
+		if err := models.DB.Unscoped().Where("short_code = ?", input.ShortCode).First(&existingURL).Error; err == nil {
 			c.JSON(http.StatusConflict, gin.H{
 				"status":  false,
 				"message": "Short code already exists",
@@ -307,13 +772,201 @@ func UpdateURL(c *gin.Context) {
 		}
 	}
 
+	if input.FolderID != nil && *input.FolderID != 0 && !workspaceHasFolder(url.WorkspaceID, *input.FolderID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Folder not found",
+		})
+		return
+	}
+
+	pathParams := []string(url.PathParams)
+	if input.PathParams != nil {
+		pathParams = *input.PathParams
+	}
+	ios, android := url.IOS, url.Android
+	if input.IOS != nil {
+		ios = *input.IOS
+	}
+	if input.Android != nil {
+		android = *input.Android
+	}
+	if err := validateLink(input.OriginalURL, pathParams, ios, android); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var schedules []models.URLSchedule
+	if input.Schedules != nil {
+		var err error
+		if schedules, err = buildSchedules(*input.Schedules); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	var rules []models.URLRule
+	if input.Rules != nil {
+		var err error
+		if rules, err = buildRules(*input.Rules); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	var variants []models.URLVariant
+	if input.Variants != nil {
+		var err error
+		if variants, err = buildVariants(*input.Variants); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	previous := linkEventData(url)
+
+	// Snapshot for the audit log, including the tags and routing the link had
+	before := url
+	models.DB.Model(&url).Association("Tags").Find(&before.Tags)
+	loadRouting(&before)
+
 	// Update URL
+	if input.OriginalURL != url.OriginalURL {
+		url.ResetHealth()
+	}
 	url.OriginalURL = input.OriginalURL
+	url.Domain = models.DomainOf(input.OriginalURL)
 	if input.ShortCode != "" {
 		url.ShortCode = input.ShortCode
 	}
+	if input.Title != nil {
+		url.Title = *input.Title
+	}
+	if input.Notes != nil {
+		url.Notes = *input.Notes
+	}
+	if input.ClearExpiry {
+		url.ExpiresAt = nil
+	} else if input.ExpiresAt != nil {
+		url.ExpiresAt = localTime(input.ExpiresAt)
+	}
+	if input.Disabled != nil {
+		url.Disabled = *input.Disabled
+	}
+	if input.FolderID != nil {
+		if *input.FolderID == 0 {
+			url.FolderID = nil
+		} else {
+			url.FolderID = input.FolderID
+		}
+	}
+	if input.ClearActiveFrom {
+		url.ActiveFrom = nil
+	} else if input.ActiveFrom != nil {
+		url.ActiveFrom = localTime(input.ActiveFrom)
+	}
+	if input.ClearFallback {
+		url.FallbackURL = ""
+	} else if input.FallbackURL != "" {
+		url.FallbackURL = input.FallbackURL
+	}
+	if input.TrackConversions != nil {
+		url.TrackConversions = *input.TrackConversions
+	}
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{input.UTMSource, &url.UTMSource},
+		{input.UTMMedium, &url.UTMMedium},
+		{input.UTMCampaign, &url.UTMCampaign},
+		{input.UTMTerm, &url.UTMTerm},
+		{input.UTMContent, &url.UTMContent},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+	if input.ForwardQuery != nil {
+		url.ForwardQuery = *input.ForwardQuery
+	}
+	if input.MatchPrefix != nil {
+		url.MatchPrefix = *input.MatchPrefix
+	}
+	if input.PathParams != nil {
+		url.PathParams = *input.PathParams
+	}
+	url.IOS, url.Android = ios, android
 
-	if err := models.DB.Save(&url).Error; err != nil {
+	// The redirects are followed again when the destination or the short
+	// code changes
+	var chain redirects.Chain
+	if input.Unwrap || url.OriginalURL != before.OriginalURL || url.ShortCode != before.ShortCode || url.MatchPrefix != before.MatchPrefix {
+		if chain, ok = followRedirects(c, &url, input.Unwrap); !ok {
+			return
+		}
+	}
+
+	// A new revision is kept whenever the destination or short code changes
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&url).Error; err != nil {
+			return err
+		}
+		if input.Schedules != nil {
+			if err := replaceSchedules(tx, url.ID, schedules); err != nil {
+				return err
+			}
+		}
+		if input.Rules != nil {
+			if err := saveRules(tx, url.ID, rules); err != nil {
+				return err
+			}
+		}
+		if input.Variants != nil {
+			if err := saveVariants(tx, url.ID, variants); err != nil {
+				return err
+			}
+		}
+		if input.Tags != nil {
+			tags, err := models.FindOrCreateTags(tx, url.WorkspaceID, int(userID), *input.Tags)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&url).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+		if url.OriginalURL == before.OriginalURL && url.ShortCode == before.ShortCode {
+			return nil
+		}
+		_, err := models.RecordURLRevision(tx, url, int(userID), nil)
+		return err
+	})
+	if errors.Is(err, errRuleNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Rule not found",
+		})
+		return
+	} else if errors.Is(err, errVariantNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Variant not found",
+		})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  false,
 			"message": "Failed to update URL",
@@ -322,17 +975,51 @@ func UpdateURL(c *gin.Context) {
 		return
 	}
 
+	models.DB.Model(&url).Association("Tags").Find(&url.Tags)
+	loadRouting(&url)
+
+	recordURLAudit(c, userID, models.AuditURLUpdated, url, auditURLFields(before), auditURLFields(url))
+
+	data := linkEventData(url)
+	data["previous"] = previous
+	notifyWebhooks(url.WorkspaceID, models.EventLinkUpdated, data)
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
 		"message": "URL updated successfully",
 		"data": gin.H{
-			"id":           url.ID,
-			"original_url": url.OriginalURL,
-			"short_code":   url.ShortCode,
-			"short_url":    "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
-			"click_count":  url.ClickCount,
-			"created_at":   url.CreatedAt,
-			"updated_at":   url.UpdatedAt,
+			"id":                url.ID,
+			"original_url":      url.OriginalURL,
+			"short_code":        url.ShortCode,
+			"short_url":         "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
+			"title":             url.Title,
+			"notes":             url.Notes,
+			"click_count":       url.ClickCount,
+			"tags":              tagNames(url.Tags),
+			"folder_id":         url.FolderID,
+			"expires_at":        url.ExpiresAt,
+			"disabled":          url.Disabled,
+			"active_from":       url.ActiveFrom,
+			"fallback_url":      url.FallbackURL,
+			"schedules":         url.Schedules,
+			"rules":             url.Rules,
+			"variants":          url.Variants,
+			"track_conversions": url.TrackConversions,
+			"utm_source":        url.UTMSource,
+			"utm_medium":        url.UTMMedium,
+			"utm_campaign":      url.UTMCampaign,
+			"utm_term":          url.UTMTerm,
+			"utm_content":       url.UTMContent,
+			"forward_query":     url.ForwardQuery,
+			"match_prefix":      url.MatchPrefix,
+			"path_params":       url.PathParams,
+			"ios":               url.IOS,
+			"android":           url.Android,
+			"final_url":         url.FinalURL,
+			"redirect_hops":     url.RedirectHops,
+			"redirect_chain":    chain.Hops,
+			"created_at":        url.CreatedAt,
+			"updated_at":        url.UpdatedAt,
 		},
 	})
 }
@@ -340,6 +1027,11 @@ func UpdateURL(c *gin.Context) {
 func DeleteURL(c *gin.Context) {
 	id := c.Param("id")
 
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	var url models.URL
 	if err := models.DB.First(&url, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -349,6 +1041,15 @@ func DeleteURL(c *gin.Context) {
 		return
 	}
 
+	if !authorizeURL(c, userID, url, models.RoleEditor) {
+		return
+	}
+
+	models.DB.Model(&url).Association("Tags").Find(&url.Tags)
+	loadRouting(&url)
+	before := auditURLFields(url)
+
+	// The link goes to the trash with its tags, so it can be restored
 	if err := models.DB.Delete(&url).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  false,
@@ -358,9 +1059,134 @@ func DeleteURL(c *gin.Context) {
 		return
 	}
 
+	recordURLAudit(c, userID, models.AuditURLDeleted, url, before, nil)
+	notifyWebhooks(url.WorkspaceID, models.EventLinkDeleted, linkEventData(url))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "URL moved to the trash",
+	})
+}
+
+// BulkUpdateURLs applies one action to several links of the workspace at once:
+// add_tags, remove_tags, move (to folder_id, 0 for the root) or delete.
+func BulkUpdateURLs(c *gin.Context) {
+	var input struct {
+		IDs      []int    `json:"ids" binding:"required,min=1"`
+		Action   string   `json:"action" binding:"required,oneof=add_tags remove_tags move delete"`
+		Tags     []string `json:"tags"`
+		FolderID *int     `json:"folder_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var urls []models.URL
+	if err := models.DB.Preload("Tags").Scopes(preloadRouting).Where("id IN ? AND workspace_id = ?", input.IDs, membership.WorkspaceID).Find(&urls).Error; err != nil || len(urls) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URLs not found",
+		})
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		switch input.Action {
+		case "add_tags":
+			tags, err := models.FindOrCreateTags(tx, membership.WorkspaceID, int(userID), input.Tags)
+			if err != nil {
+				return err
+			}
+			for i := range urls {
+				if err := tx.Model(&urls[i]).Association("Tags").Append(tags); err != nil {
+					return err
+				}
+			}
+		case "remove_tags":
+			// Tags that don't exist are on no link, there is nothing to create
+			tags, err := models.FindTags(tx, membership.WorkspaceID, input.Tags)
+			if err != nil || len(tags) == 0 {
+				return err
+			}
+			for i := range urls {
+				if err := tx.Model(&urls[i]).Association("Tags").Delete(tags); err != nil {
+					return err
+				}
+			}
+		case "move":
+			var folderID *int
+			if input.FolderID != nil && *input.FolderID != 0 {
+				if !workspaceHasFolder(membership.WorkspaceID, *input.FolderID) {
+					return errFolderNotFound
+				}
+				folderID = input.FolderID
+			}
+			return tx.Model(&models.URL{}).Where("id IN ? AND workspace_id = ?", input.IDs, membership.WorkspaceID).Update("folder_id", folderID).Error
+		case "delete":
+			return tx.Delete(&urls).Error
+		}
+		return nil
+	})
+
+	if errors.Is(err, errFolderNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Folder not found",
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update URLs",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ids := make([]int, len(urls))
+	for i, url := range urls {
+		ids[i] = url.ID
+	}
+
+	if input.Action == "delete" {
+		for _, url := range urls {
+			recordURLAudit(c, userID, models.AuditURLDeleted, url, auditURLFields(url), nil)
+			notifyWebhooks(url.WorkspaceID, models.EventLinkDeleted, linkEventData(url))
+		}
+	} else {
+		var updated []models.URL
+		models.DB.Preload("Tags").Scopes(preloadRouting).Where("id IN ?", ids).Find(&updated)
+		before := make(map[int]models.URL, len(urls))
+		for _, url := range urls {
+			before[url.ID] = url
+		}
+		for _, url := range updated {
+			recordURLAudit(c, userID, models.AuditURLUpdated, url, auditURLFields(before[url.ID]), auditURLFields(url))
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
-		"message": "URL deleted successfully",
+		"message": "URLs updated successfully",
+		"data": gin.H{
+			"action":  input.Action,
+			"updated": ids,
+		},
 	})
 }
 
@@ -394,49 +1220,84 @@ func GetAnalytics(c *gin.Context) {
 
 	userID := uint(claims["user_id"].(float64))
 
+	membership, ok := requireWorkspace(c, userID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
 	// Get filter parameters
-	urlFilter := c.Query("url")
+	filter, err := parseLinkFilter(c, membership.WorkspaceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folder not found"})
+		return
+	}
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
-	period := c.DefaultQuery("period", "week") // week, month, year
+	period := c.DefaultQuery("period", "week") // day, week, month, year
+
+	// Granularity defaults from the period: hour, day, week (ISO) or month
+	granularity := c.DefaultQuery("granularity", defaultGranularity(period))
+	if !validGranularity(granularity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid granularity. Use hour, day, week or month"})
+		return
+	}
 
-	// Parse date filters
+	// Links can also be grouped by their UTM campaign
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != "utm_campaign" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group_by. Use utm_campaign"})
+		return
+	}
+
+	// Buckets are computed in the requested timezone, falling back to the user's own
+	loc, err := analyticsLocation(c.Query("tz"), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tz. Use an IANA timezone such as Asia/Jakarta"})
+		return
+	}
+
+	// Parse date filters in that timezone; the end of the range is exclusive
 	var startTime, endTime time.Time
 	var parseErr error
 
 	if startDate != "" {
-		startTime, parseErr = time.Parse("2006-01-02", startDate)
+		startTime, parseErr = time.ParseInLocation("2006-01-02", startDate, loc)
 		if parseErr != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
 			return
 		}
+	} else if period == "day" {
+		// Default to today for the day view
+		startTime = startOfDay(time.Now().In(loc))
 	} else {
 		// Default to last 30 days if no start date provided
-		startTime = time.Now().AddDate(0, 0, -30)
+		startTime = startOfDay(time.Now().In(loc).AddDate(0, 0, -30))
 	}
 
 	if endDate != "" {
-		endTime, parseErr = time.Parse("2006-01-02", endDate)
+		endTime, parseErr = time.ParseInLocation("2006-01-02", endDate, loc)
 		if parseErr != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format. Use YYYY-MM-DD"})
 			return
 		}
-		// Set end time to end of day
-		endTime = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 23, 59, 59, 0, endTime.Location())
+		// Include the whole end day
+		endTime = endTime.AddDate(0, 0, 1)
+	} else if period == "day" {
+		endTime = startTime.AddDate(0, 0, 1)
 	} else {
 		// Default to now if no end date provided
-		endTime = time.Now()
+		endTime = time.Now().In(loc)
 	}
 
-	// Get user's URLs
-	var urls []models.URL
-	query := models.DB.Where("user_id = ?", userID)
-
-	// Apply URL filter if provided
-	if urlFilter != "" {
-		query = query.Where("short_code = ?", urlFilter)
+	if !endTime.After(startTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
 	}
 
+	// Get the workspace's URLs, narrowed by the url / tag / folder filters
+	var urls []models.URL
+	query := filter.apply(models.DB.Where("urls.workspace_id = ?", membership.WorkspaceID))
+
 	if err := query.Find(&urls).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  false,
@@ -445,205 +1306,201 @@ func GetAnalytics(c *gin.Context) {
 		return
 	}
 
-	// Calculate total clicks with filters (only user's URLs)
-	var totalClicks int64
-	clickQuery := models.DB.Table("clicks").
-		Joins("JOIN urls ON clicks.url_id = urls.id").
-		Where("urls.user_id = ?", userID).
-		Where("clicks.clicked_at >= ?", startTime).
-		Where("clicks.clicked_at <= ?", endTime)
-
-	// Apply URL filter if provided
-	if urlFilter != "" {
-		clickQuery = clickQuery.Where("urls.short_code = ?", urlFilter)
-	}
-
-	clickQuery.Count(&totalClicks)
-
-	// Generate time-based click data based on period and date range
-	var timeBasedClicks []gin.H
-	var timeLabels []string
-
-	switch period {
-	case "day":
-		// Last 24 hours from date range
-		timeBasedClicks = make([]gin.H, 24)
-		timeLabels = make([]string, 24)
-		for i := 0; i < 24; i++ {
-			hour := startTime.Add(time.Duration(i) * time.Hour)
-			timeLabels[i] = hour.Format("15:04")
-			timeBasedClicks[i] = gin.H{
-				"time":   timeLabels[i],
-				"clicks": 0,
-			}
-		}
-	case "week":
-		// Use the actual date range provided by user
-		days := int(endTime.Sub(startTime).Hours()/24) + 1
-		if days > 365 { // Limit to prevent memory issues
-			days = 365
-		}
-		timeBasedClicks = make([]gin.H, days)
-		timeLabels = make([]string, days)
-		for i := 0; i < days; i++ {
-			date := startTime.AddDate(0, 0, i)
-			timeLabels[i] = date.Format("2006-01-02")
-			timeBasedClicks[i] = gin.H{
-				"time":   timeLabels[i],
-				"clicks": 0,
-			}
-		}
-	case "month":
-		// Use the actual date range provided by user
-		days := int(endTime.Sub(startTime).Hours()/24) + 1
-		if days > 365 { // Limit to prevent memory issues
-			days = 365
-		}
-		timeBasedClicks = make([]gin.H, days)
-		timeLabels = make([]string, days)
-		for i := 0; i < days; i++ {
-			date := startTime.AddDate(0, 0, i)
-			timeLabels[i] = date.Format("2006-01-02")
-			timeBasedClicks[i] = gin.H{
-				"time":   timeLabels[i],
-				"clicks": 0,
-			}
-		}
-	case "year":
-		// Last 12 months
-		timeBasedClicks = make([]gin.H, 12)
-		timeLabels = make([]string, 12)
-		for i := 0; i < 12; i++ {
-			month := startTime.AddDate(0, i, 0)
-			if month.After(endTime) {
-				break
-			}
-			timeLabels[i] = month.Format("2006-01")
-			timeBasedClicks[i] = gin.H{
-				"time":   timeLabels[i],
-				"clicks": 0,
-			}
-		}
-	default:
-		// Default to week - use date range
-		days := int(endTime.Sub(startTime).Hours()/24) + 1
-		if days > 365 {
-			days = 365
-		}
-		timeBasedClicks = make([]gin.H, days)
-		timeLabels = make([]string, days)
-		for i := 0; i < days; i++ {
-			date := startTime.AddDate(0, 0, i)
-			timeLabels[i] = date.Format("2006-01-02")
-			timeBasedClicks[i] = gin.H{
-				"time":   timeLabels[i],
-				"clicks": 0,
-			}
-		}
+	buckets := newAnalyticsBuckets(granularity, loc, startTime, endTime)
+	rollups := func() *gorm.DB {
+		return workspaceRollupQuery(buckets, membership.WorkspaceID, filter)
 	}
 
-	// Get actual click data for the specified period (only user's URLs)
-	var clicks []models.Click
-	clickDataQuery := models.DB.Table("clicks").
-		Joins("JOIN urls ON clicks.url_id = urls.id").
-		Where("urls.user_id = ?", userID).
-		Where("clicks.clicked_at >= ?", startTime).
-		Where("clicks.clicked_at <= ?", endTime)
+	// Calculate total clicks with filters (only the workspace's URLs)
+	var totalClicks int64
+	rollups().Select("COALESCE(SUM(r.clicks), 0)").Scan(&totalClicks)
 
-	// Apply URL filter if provided
-	if urlFilter != "" {
-		clickDataQuery = clickDataQuery.Where("urls.short_code = ?", urlFilter)
+	// Count clicks per time period
+	var bucketRows []struct {
+		Bucket time.Time
+		Clicks int64
 	}
+	rollups().Select("r.bucket AS bucket, SUM(r.clicks) AS clicks").Group("r.bucket").Scan(&bucketRows)
 
-	clickDataQuery.Find(&clicks)
+	// Unique visitors are estimated by merging the rollup sketches
+	totalVisitors, bucketVisitors, urlVisitors := visitorSketches(rollups(), buckets)
 
-	// Count clicks per time period
-	for _, click := range clicks {
-		var timeKey string
-		var timeIndex int
-
-		switch period {
-		case "day":
-			timeKey = click.ClickedAt.Format("15:04")
-			for i, label := range timeLabels {
-				if label == timeKey {
-					timeIndex = i
-					break
-				}
-			}
-		case "week", "month":
-			timeKey = click.ClickedAt.Format("2006-01-02")
-			for i, label := range timeLabels {
-				if label == timeKey {
-					timeIndex = i
-					break
-				}
-			}
-		case "year":
-			timeKey = click.ClickedAt.Format("2006-01")
-			for i, label := range timeLabels {
-				if label == timeKey {
-					timeIndex = i
-					break
-				}
-			}
+	timeBasedClicks := make([]gin.H, len(buckets.labels))
+	counts := make([]int64, len(buckets.labels))
+	for _, row := range bucketRows {
+		if i, ok := buckets.indexOf(row.Bucket); ok {
+			counts[i] += row.Clicks
 		}
-
-		if timeIndex < len(timeBasedClicks) {
-			currentClicks := timeBasedClicks[timeIndex]["clicks"].(int)
-			timeBasedClicks[timeIndex] = gin.H{
-				"time":   timeLabels[timeIndex],
-				"clicks": currentClicks + 1,
-			}
+	}
+	for i, label := range buckets.labels {
+		timeBasedClicks[i] = gin.H{
+			"time":            label,
+			"clicks":          counts[i],
+			"unique_visitors": bucketVisitors[i].Count(),
 		}
 	}
 
+	// Per-URL click counts within the date range, in a single query
+	var urlRows []struct {
+		URLID  int
+		Clicks int64
+	}
+	rollups().Select("r.url_id AS url_id, SUM(r.clicks) AS clicks").Group("r.url_id").Scan(&urlRows)
+
+	clicksByURL := make(map[int]int64, len(urlRows))
+	for _, row := range urlRows {
+		clicksByURL[row.URLID] = row.Clicks
+	}
+
+	// Conversions reported within the date range
+	conversions := conversionTotals(membership.WorkspaceID, filter, buckets.from, buckets.to)
+	var totalConversions, convertedClicks int64
+	var totalRevenue float64
+	for _, total := range conversions {
+		totalConversions += total.Conversions
+		convertedClicks += total.ConvertedClicks
+		totalRevenue += total.Revenue
+	}
+
 	// Prepare URL stats with filters
 	urlStats := make([]gin.H, len(urls))
 	for i, url := range urls {
-		// Get actual click count from clicks table for each URL within date range
-		var clickCount int64
-		urlClickQuery := models.DB.Table("clicks").Where("url_id = ?", url.ID).Where("clicked_at >= ?", startTime).Where("clicked_at <= ?", endTime)
-		urlClickQuery.Count(&clickCount)
+		var uniqueVisitors int64
+		if sketch, ok := urlVisitors[url.ID]; ok {
+			uniqueVisitors = sketch.Count()
+		}
 
 		urlStats[i] = gin.H{
-			"id":           url.ID,
-			"short_code":   url.ShortCode,
-			"original_url": url.OriginalURL,
-			"short_url":    "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
-			"click_count":  int(clickCount),
-			"created_at":   url.CreatedAt,
+			"id":              url.ID,
+			"short_code":      url.ShortCode,
+			"original_url":    url.OriginalURL,
+			"short_url":       "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
+			"click_count":     clicksByURL[url.ID],
+			"unique_visitors": uniqueVisitors,
+			"conversions":     conversions[url.ID].Conversions,
+			"revenue":         conversions[url.ID].Revenue,
+			"conversion_rate": rate(conversions[url.ID].ConvertedClicks, clicksByURL[url.ID]),
+			"created_at":      url.CreatedAt,
 		}
 	}
 
-	// Determine response data key based on period
+	// Breakdown by the rollup dimensions
+	breakdown := gin.H{}
+	for key, column := range map[string]string{"countries": "country", "referrers": "referrer", "devices": "device"} {
+		breakdown[key] = topDimensionValues(rollups(), column, 10)
+	}
+
+	// Determine response data key based on granularity
 	var dataKey string
-	switch period {
-	case "day":
+	switch granularity {
+	case "hour":
 		dataKey = "hourlyClicks"
 	case "week":
-		dataKey = "dailyClicks"
+		dataKey = "weeklyClicks"
 	case "month":
-		dataKey = "dailyClicks"
-	case "year":
 		dataKey = "monthlyClicks"
 	default:
 		dataKey = "dailyClicks"
 	}
 
+	filters := gin.H{
+		"workspace_id": membership.WorkspaceID,
+		"url":          filter.ShortCode,
+		"tag":          filter.Tag,
+		"folder":       filter.Folder,
+		"utm_campaign": filter.Campaign,
+		"group_by":     groupBy,
+		"start_date":   startTime.Format("2006-01-02"),
+		"end_date":     endTime.Add(-time.Nanosecond).Format("2006-01-02"),
+		"period":       period,
+		"granularity":  granularity,
+		"tz":           loc.String(),
+	}
+	data := gin.H{
+		"totalClicks":         totalClicks,
+		"totalUniqueVisitors": totalVisitors.Count(),
+		"totalConversions":    totalConversions,
+		"totalRevenue":        totalRevenue,
+		"conversionRate":      rate(convertedClicks, totalClicks),
+		dataKey:               timeBasedClicks,
+		"urlStats":            urlStats,
+		"breakdown":           breakdown,
+		"annotations":         revisionAnnotations(membership.WorkspaceID, filter, buckets),
+		"rules":               ruleBreakdown(membership.WorkspaceID, filter, buckets.from, buckets.to),
+	}
+	if groupBy == "utm_campaign" {
+		data["campaigns"] = campaignBreakdown(urls, clicksByURL, urlVisitors, conversions)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
 		"message": "Analytics retrieved successfully",
-		"filters": gin.H{
-			"url":        urlFilter,
-			"start_date": startTime.Format("2006-01-02"),
-			"end_date":   endTime.Format("2006-01-02"),
-			"period":     period,
-		},
-		"data": gin.H{
-			"totalClicks": totalClicks,
-			dataKey:       timeBasedClicks,
-			"urlStats":    urlStats,
-		},
+		"filters": filters,
+		"data":    data,
 	})
 }
+
+// localTime converts a time from a request body to server time, which is how
+// gorm stores timestamps, so they compare correctly in SQLite.
+func localTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.Local()
+	return &local
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// findURLsInOrder loads URLs with their tags, keeping the order of ids.
+func findURLsInOrder(ids []int) ([]models.URL, error) {
+	if len(ids) == 0 {
+		return []models.URL{}, nil
+	}
+
+	var found []models.URL
+	if err := models.DB.Preload("Tags").Scopes(preloadRouting).Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]models.URL, len(found))
+	for _, url := range found {
+		byID[url.ID] = url
+	}
+
+	urls := make([]models.URL, 0, len(ids))
+	for _, id := range ids {
+		if url, ok := byID[id]; ok {
+			urls = append(urls, url)
+		}
+	}
+	return urls, nil
+}
+
+// authorizeURL checks the user's role in the workspace of a link, writing the
+// error response if it isn't enough. Links of other workspaces are not found.
+func authorizeURL(c *gin.Context, userID uint, url models.URL, minRole string) bool {
+	membership, err := models.FindMembership(url.WorkspaceID, int(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "URL not found",
+		})
+		return false
+	}
+
+	if !models.RoleAtLeast(membership.Role, minRole) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "Your role in this workspace does not allow this action",
+		})
+		return false
+	}
+
+	return true
+}
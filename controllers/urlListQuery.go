@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// urlSortColumns maps the sort parameter of GetURLs to the SQL expression
+// and its default direction.
+var urlSortColumns = map[string]struct {
+	expr    string
+	order   string
+	numeric bool
+	time    bool
+}{
+	"created":      {"urls.created_at", "desc", false, true},
+	"clicks":       {"urls.click_count", "desc", true, false},
+	"last_clicked": {"COALESCE(urls.last_clicked_at, '')", "desc", false, true},
+	"alphabetical": {"LOWER(COALESCE(NULLIF(urls.title, ''), urls.original_url))", "asc", false, false},
+}
+
+// urlListQuery holds the search, filter and sort parameters of GetURLs.
+type urlListQuery struct {
+	Search      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MinClicks   *int
+	MaxClicks   *int
+	Status      string // active, scheduled, expired, disabled or blocked
+	Health      string // healthy, broken or unknown
+	Domain      string
+	Sort        string
+	Order       string
+}
+
+func parseURLListQuery(c *gin.Context) (urlListQuery, error) {
+	q := urlListQuery{
+		Search: strings.TrimSpace(c.Query("q")),
+		Status: c.Query("status"),
+		Health: c.Query("health"),
+		Domain: strings.TrimPrefix(strings.ToLower(strings.TrimSpace(c.Query("domain"))), "www."),
+		Sort:   c.DefaultQuery("sort", "created"),
+		Order:  strings.ToLower(c.Query("order")),
+	}
+
+	if v := c.Query("created_from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return q, fmt.Errorf("invalid created_from format, use YYYY-MM-DD")
+		}
+		q.CreatedFrom = &t
+	}
+	if v := c.Query("created_to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return q, fmt.Errorf("invalid created_to format, use YYYY-MM-DD")
+		}
+		q.CreatedTo = &t
+	}
+	if v := c.Query("min_clicks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return q, fmt.Errorf("min_clicks must be a number")
+		}
+		q.MinClicks = &n
+	}
+	if v := c.Query("max_clicks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return q, fmt.Errorf("max_clicks must be a number")
+		}
+		q.MaxClicks = &n
+	}
+
+	if q.Status != "" && q.Status != "active" && q.Status != "scheduled" && q.Status != "expired" && q.Status != "disabled" && q.Status != "blocked" {
+		return q, fmt.Errorf("status must be active, scheduled, expired, disabled or blocked")
+	}
+
+	if q.Health != "" && q.Health != models.HealthHealthy && q.Health != models.HealthBroken && q.Health != models.HealthUnknown {
+		return q, fmt.Errorf("health must be healthy, broken or unknown")
+	}
+
+	sort, ok := urlSortColumns[q.Sort]
+	if !ok {
+		return q, fmt.Errorf("sort must be one of created, clicks, last_clicked, alphabetical")
+	}
+	if q.Order == "" {
+		q.Order = sort.order
+	}
+	if q.Order != "asc" && q.Order != "desc" {
+		return q, fmt.Errorf("order must be asc or desc")
+	}
+
+	return q, nil
+}
+
+// apply adds the search and filter conditions to a query on the urls table.
+func (q urlListQuery) apply(query *gorm.DB) *gorm.DB {
+	if terms := searchTerms(q.Search); len(terms) > 0 {
+		if models.FTSEnabled {
+			// Every term must match, as a prefix, in any of the indexed columns
+			match := make([]string, len(terms))
+			for i, term := range terms {
+				match[i] = `"` + term + `"*`
+			}
+			query = query.Where("urls.id IN (SELECT rowid FROM urls_fts WHERE urls_fts MATCH ?)", strings.Join(match, " "))
+		} else {
+			for _, term := range terms {
+				like := "%" + term + "%"
+				query = query.Where("(urls.original_url LIKE ? OR urls.short_code LIKE ? OR urls.title LIKE ? OR urls.notes LIKE ?)", like, like, like, like)
+			}
+		}
+	}
+
+	if q.CreatedFrom != nil {
+		query = query.Where("urls.created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		query = query.Where("urls.created_at < ?", q.CreatedTo.AddDate(0, 0, 1))
+	}
+	if q.MinClicks != nil {
+		query = query.Where("urls.click_count >= ?", *q.MinClicks)
+	}
+	if q.MaxClicks != nil {
+		query = query.Where("urls.click_count <= ?", *q.MaxClicks)
+	}
+
+	switch q.Status {
+	case "active":
+		query = query.Where("urls.disabled = ? AND urls.blocked = ?", false, false).
+			Where("(urls.expires_at IS NULL OR urls.expires_at > ?)", time.Now()).
+			Where("(urls.active_from IS NULL OR urls.active_from <= ?)", time.Now())
+	case "scheduled":
+		query = query.Where("urls.active_from > ?", time.Now())
+	case "expired":
+		query = query.Where("urls.expires_at IS NOT NULL AND urls.expires_at <= ?", time.Now())
+	case "disabled":
+		query = query.Where("urls.disabled = ?", true)
+	case "blocked":
+		query = query.Where("urls.blocked = ?", true)
+	}
+
+	if q.Health != "" {
+		query = query.Where("urls.health_status = ?", q.Health)
+	}
+
+	if q.Domain != "" {
+		// Subdomains match too
+		query = query.Where("(urls.domain = ? OR urls.domain LIKE ?)", q.Domain, "%."+q.Domain)
+	}
+
+	return query
+}
+
+// orderBy returns the ORDER BY clause, using the id as tie breaker so the
+// order is stable. It is the order of the cursor pages too.
+func (q urlListQuery) orderBy() string {
+	expr, _ := q.keysetSort().keyExpr()
+	return expr + " " + q.Order + ", urls.id " + q.Order
+}
+
+// searchTerms splits the search input into words, dropping the characters
+// that have a meaning in FTS5 query syntax.
+func searchTerms(search string) []string {
+	return strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// keysetSort returns the sort order for cursor pagination.
+func (q urlListQuery) keysetSort() keysetSort {
+	column := urlSortColumns[q.Sort]
+	return keysetSort{
+		Name:     q.Sort,
+		Expr:     column.expr,
+		Numeric:  column.numeric,
+		Time:     column.time,
+		IDColumn: "urls.id",
+		Order:    q.Order,
+	}
+}
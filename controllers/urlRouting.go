@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScheduleInput is a time-based destination as sent by the client. The
+// schedules of a link are always sent as a whole list, in order of priority.
+type ScheduleInput struct {
+	DestinationURL string     `json:"destination_url" binding:"required,url"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+}
+
+// buildSchedules validates the schedules and numbers them in the given order.
+func buildSchedules(inputs []ScheduleInput) ([]models.URLSchedule, error) {
+	if len(inputs) > models.MaxURLSchedules {
+		return nil, fmt.Errorf("a link can have at most %d schedules", models.MaxURLSchedules)
+	}
+
+	schedules := make([]models.URLSchedule, len(inputs))
+	for i, input := range inputs {
+		if input.StartsAt == nil && input.EndsAt == nil {
+			return nil, fmt.Errorf("schedule %d needs starts_at or ends_at", i+1)
+		}
+		if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+			return nil, fmt.Errorf("schedule %d must end after it starts", i+1)
+		}
+		schedules[i] = models.URLSchedule{
+			Position:       i,
+			DestinationURL: input.DestinationURL,
+			StartsAt:       localTime(input.StartsAt),
+			EndsAt:         localTime(input.EndsAt),
+		}
+	}
+	return schedules, nil
+}
+
+// replaceSchedules swaps the schedules of a link for new ones.
+func replaceSchedules(tx *gorm.DB, urlID int, schedules []models.URLSchedule) error {
+	if err := tx.Where("url_id = ?", urlID).Delete(&models.URLSchedule{}).Error; err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		return nil
+	}
+	for i := range schedules {
+		schedules[i].URLID = urlID
+	}
+	return tx.Create(&schedules).Error
+}
+
+var errRuleNotFound = errors.New("rule not found")
+
+// RuleInput is a routing rule as sent by the client. Like schedules, the
+// rules of a link are sent as a whole list, in order of priority.
+type RuleInput struct {
+	ID             int      `json:"id"` // Keeps an existing rule, and its analytics, on update
+	Name           string   `json:"name"`
+	DestinationURL string   `json:"destination_url" binding:"required,url"`
+	Countries      []string `json:"countries"`
+	Devices        []string `json:"devices"`
+	OS             []string `json:"os"`
+	Languages      []string `json:"languages"`
+	Referrers      []string `json:"referrers"`
+	QueryParams    []string `json:"query_params"`
+}
+
+// buildRules validates and normalizes the rules and numbers them in the
+// given order.
+func buildRules(inputs []RuleInput) ([]models.URLRule, error) {
+	if len(inputs) > models.MaxURLRules {
+		return nil, fmt.Errorf("a link can have at most %d rules", models.MaxURLRules)
+	}
+
+	rules := make([]models.URLRule, len(inputs))
+	for i, input := range inputs {
+		rule := models.URLRule{
+			ID:             input.ID,
+			Position:       i,
+			Name:           strings.TrimSpace(input.Name),
+			DestinationURL: input.DestinationURL,
+			Countries:      normalizeValues(input.Countries, strings.ToUpper),
+			Devices:        normalizeValues(input.Devices, strings.ToLower),
+			OSes:           normalizeValues(input.OS, strings.ToLower),
+			Languages:      normalizeValues(input.Languages, strings.ToLower),
+			Referrers: normalizeValues(input.Referrers, func(host string) string {
+				return strings.TrimPrefix(strings.ToLower(host), "www.")
+			}),
+			QueryParams: normalizeValues(input.QueryParams, func(param string) string { return param }),
+		}
+
+		if !rule.HasConditions() {
+			return nil, fmt.Errorf("rule %d needs at least one condition", i+1)
+		}
+		for _, country := range rule.Countries {
+			if len(country) != 2 {
+				return nil, fmt.Errorf("rule %d: countries must be ISO 3166 alpha-2 codes", i+1)
+			}
+		}
+		for _, device := range rule.Devices {
+			if !models.StringList(models.RuleDevices).Contains(device) {
+				return nil, fmt.Errorf("rule %d: devices must be one of %s", i+1, strings.Join(models.RuleDevices, ", "))
+			}
+		}
+		for _, os := range rule.OSes {
+			if !models.StringList(models.RuleOSes).Contains(os) {
+				return nil, fmt.Errorf("rule %d: os must be one of %s", i+1, strings.Join(models.RuleOSes, ", "))
+			}
+		}
+		for _, param := range rule.QueryParams {
+			if strings.HasPrefix(param, "=") {
+				return nil, fmt.Errorf("rule %d: query_params must be key or key=value", i+1)
+			}
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+// normalizeValues trims and normalizes condition values, dropping empty ones.
+func normalizeValues(values []string, normalize func(string) string) models.StringList {
+	normalized := models.StringList{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			normalized = append(normalized, normalize(value))
+		}
+	}
+	return normalized
+}
+
+// saveRules makes the given rules the rules of a link. Rules with an ID are
+// updated in place, so clicks keep pointing at them; the others are created
+// and rules left out are deleted.
+func saveRules(tx *gorm.DB, urlID int, rules []models.URLRule) error {
+	keep := []int{0}
+	for _, rule := range rules {
+		if rule.ID != 0 {
+			keep = append(keep, rule.ID)
+		}
+	}
+	if err := tx.Where("url_id = ? AND id NOT IN ?", urlID, keep).Delete(&models.URLRule{}).Error; err != nil {
+		return err
+	}
+
+	for i := range rules {
+		rules[i].URLID = urlID
+		if rules[i].ID == 0 {
+			if err := tx.Create(&rules[i]).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		result := tx.Model(&models.URLRule{}).Where("id = ? AND url_id = ?", rules[i].ID, urlID).
+			Select("position", "name", "destination_url", "countries", "devices", "oses", "languages", "referrers", "query_params").
+			Updates(&rules[i])
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRuleNotFound
+		}
+	}
+	return nil
+}
+
+var errVariantNotFound = errors.New("variant not found")
+
+// VariantInput is an A/B variant as sent by the client, as a whole list.
+type VariantInput struct {
+	ID             int    `json:"id"` // Keeps an existing variant, and its stats, on update
+	Name           string `json:"name"`
+	DestinationURL string `json:"destination_url" binding:"required,url"`
+	Weight         *int   `json:"weight"` // Defaults to 1
+}
+
+// buildVariants validates the variants of an A/B split. A split needs at
+// least two variants and one of them with a weight.
+func buildVariants(inputs []VariantInput) ([]models.URLVariant, error) {
+	if len(inputs) == 0 {
+		return []models.URLVariant{}, nil
+	}
+	if len(inputs) < 2 || len(inputs) > models.MaxURLVariants {
+		return nil, fmt.Errorf("an A/B split needs between 2 and %d variants", models.MaxURLVariants)
+	}
+
+	variants := make([]models.URLVariant, len(inputs))
+	total := 0
+	for i, input := range inputs {
+		weight := 1
+		if input.Weight != nil {
+			weight = *input.Weight
+		}
+		if weight < 0 || weight > models.MaxVariantWeight {
+			return nil, fmt.Errorf("variant %d: weight must be between 0 and %d", i+1, models.MaxVariantWeight)
+		}
+		total += weight
+
+		name := strings.TrimSpace(input.Name)
+		if name == "" {
+			name = string(rune('A' + i))
+		}
+		variants[i] = models.URLVariant{
+			ID:             input.ID,
+			Position:       i,
+			Name:           name,
+			DestinationURL: input.DestinationURL,
+			Weight:         weight,
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("at least one variant needs a weight")
+	}
+	return variants, nil
+}
+
+// saveVariants makes the given variants the A/B split of a link, updating the
+// ones with an ID in place like saveRules.
+func saveVariants(tx *gorm.DB, urlID int, variants []models.URLVariant) error {
+	keep := []int{0}
+	for _, variant := range variants {
+		if variant.ID != 0 {
+			keep = append(keep, variant.ID)
+		}
+	}
+	if err := tx.Where("url_id = ? AND id NOT IN ?", urlID, keep).Delete(&models.URLVariant{}).Error; err != nil {
+		return err
+	}
+
+	for i := range variants {
+		variants[i].URLID = urlID
+		if variants[i].ID == 0 {
+			if err := tx.Create(&variants[i]).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		result := tx.Model(&models.URLVariant{}).Where("id = ? AND url_id = ?", variants[i].ID, urlID).
+			Select("position", "name", "destination_url", "weight").
+			Updates(&variants[i])
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errVariantNotFound
+		}
+	}
+	return nil
+}
+
+// preloadRouting preloads the schedules, rules and variants of links in
+// priority order.
+func preloadRouting(db *gorm.DB) *gorm.DB {
+	return db.Preload("Schedules", byPosition).Preload("Rules", byPosition).Preload("Variants", byPosition)
+}
+
+func byPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+// loadRouting fills in the schedules, rules and variants of a link in
+// priority order.
+func loadRouting(url *models.URL) {
+	url.Schedules = []models.URLSchedule{}
+	url.Rules = []models.URLRule{}
+	url.Variants = []models.URLVariant{}
+	models.DB.Where("url_id = ?", url.ID).Order("position").Find(&url.Schedules)
+	models.DB.Where("url_id = ?", url.ID).Order("position").Find(&url.Rules)
+	models.DB.Where("url_id = ?", url.ID).Order("position").Find(&url.Variants)
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedirectBeforeActivation(t *testing.T) {
+	setupTestDB(t)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	schedule := models.URLSchedule{DestinationURL: "https://scheduled.example.com", StartsAt: &past}
+
+	tests := []struct {
+		name         string
+		link         models.URL
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "not live, with a fallback",
+			link:         models.URL{ShortCode: "soon", OriginalURL: "https://launch.example.com", ActiveFrom: &future, FallbackURL: "https://teaser.example.com"},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://teaser.example.com",
+		},
+		{
+			name:       "not live, without a fallback",
+			link:       models.URL{ShortCode: "hidden", OriginalURL: "https://launch.example.com", ActiveFrom: &future},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:         "not live, schedules wait too",
+			link:         models.URL{ShortCode: "queued", OriginalURL: "https://launch.example.com", ActiveFrom: &future, FallbackURL: "https://teaser.example.com", Schedules: []models.URLSchedule{schedule}},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://teaser.example.com",
+		},
+		{
+			name:         "live",
+			link:         models.URL{ShortCode: "live", OriginalURL: "https://launch.example.com", ActiveFrom: &past, FallbackURL: "https://teaser.example.com"},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://launch.example.com",
+		},
+		{
+			name:         "live, in a schedule",
+			link:         models.URL{ShortCode: "sale", OriginalURL: "https://launch.example.com", ActiveFrom: &past, Schedules: []models.URLSchedule{schedule}},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://scheduled.example.com",
+		},
+	}
+
+	r := gin.New()
+	r.GET("/:shortCode", RedirectURL)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := models.DB.Create(&tt.link).Error; err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.link.ShortCode, nil))
+			if w.Code != tt.wantStatus || w.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("GET /%s = %d to %q, want %d to %q", tt.link.ShortCode, w.Code, w.Header().Get("Location"), tt.wantStatus, tt.wantLocation)
+			}
+			var clicks int64
+			models.DB.Model(&models.Click{}).Where("url_id = ?", tt.link.ID).Count(&clicks)
+			if redirected := tt.wantStatus == http.StatusFound; (clicks == 1) != redirected {
+				t.Errorf("clicks = %d after status %d", clicks, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"backend-go/safehttp"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookInput struct {
+	TargetURL string   `json:"target_url" binding:"required,url"`
+	Events    []string `json:"events" binding:"required,min=1"`
+	Secret    string   `json:"secret"`
+	Active    *bool    `json:"active"`
+}
+
+func CreateWebhook(c *gin.Context) {
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	events, err := validateWebhookInput(input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		rand.Read(b)
+		secret = hex.EncodeToString(b)
+	}
+
+	webhook := models.Webhook{
+		UserID:      int(userID),
+		WorkspaceID: membership.WorkspaceID,
+		TargetURL:   input.TargetURL,
+		Events:      strings.Join(events, ","),
+		Secret:      secret,
+		Active:      input.Active == nil || *input.Active,
+	}
+
+	if err := models.DB.Create(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to create webhook",
+		})
+		return
+	}
+
+	data := webhookResponse(webhook)
+	data["secret"] = webhook.Secret // Only returned once, store it on the receiving side
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Webhook created successfully",
+		"data":    data,
+	})
+}
+
+func GetWebhooks(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := models.DB.Where("workspace_id = ?", membership.WorkspaceID).Order("created_at DESC").Find(&webhooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve webhooks",
+		})
+		return
+	}
+
+	data := make([]gin.H, len(webhooks))
+	for i, webhook := range webhooks {
+		data[i] = webhookResponse(webhook)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Webhooks retrieved successfully",
+		"data":    data,
+	})
+}
+
+func UpdateWebhook(c *gin.Context) {
+	webhook, ok := findWorkspaceWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	events, err := validateWebhookInput(input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	webhook.TargetURL = input.TargetURL
+	webhook.Events = strings.Join(events, ",")
+	if input.Secret != "" {
+		webhook.Secret = input.Secret
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+
+	if err := models.DB.Save(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update webhook",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Webhook updated successfully",
+		"data":    webhookResponse(webhook),
+	})
+}
+
+func DeleteWebhook(c *gin.Context) {
+	webhook, ok := findWorkspaceWebhook(c)
+	if !ok {
+		return
+	}
+
+	// Drop pending deliveries together with the webhook
+	models.DB.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{})
+
+	if err := models.DB.Delete(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to delete webhook",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Webhook deleted successfully",
+	})
+}
+
+func GetWebhookDeliveries(c *gin.Context) {
+	webhook, ok := findWorkspaceWebhook(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := models.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve deliveries",
+		})
+		return
+	}
+
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Deliveries retrieved successfully",
+		"data": gin.H{
+			"deliveries": deliveries,
+			"pagination": gin.H{
+				"current_page": page,
+				"per_page":     limit,
+				"total":        total,
+				"total_pages":  totalPages,
+				"has_next":     page < int(totalPages),
+				"has_prev":     page > 1,
+			},
+		},
+	})
+}
+
+// RedeliverWebhook queues a new delivery with the payload of an earlier one.
+// The original delivery stays in the log untouched.
+func RedeliverWebhook(c *gin.Context) {
+	webhook, ok := findWorkspaceWebhook(c)
+	if !ok {
+		return
+	}
+
+	var original models.WebhookDelivery
+	if err := models.DB.Where("id = ? AND webhook_id = ?", c.Param("deliveryId"), webhook.ID).First(&original).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Delivery not found",
+		})
+		return
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+
+	if err := models.DB.Create(&delivery).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to queue redelivery",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  true,
+		"message": "Redelivery queued",
+		"data":    delivery,
+	})
+}
+
+// findWorkspaceWebhook loads the webhook in the :id route parameter from the
+// request's workspace. Managing webhooks takes the admin role.
+func findWorkspaceWebhook(c *gin.Context) (models.Webhook, bool) {
+	var webhook models.Webhook
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return webhook, false
+	}
+
+	membership, ok := requireWorkspace(c, userID, models.RoleAdmin)
+	if !ok {
+		return webhook, false
+	}
+
+	if err := models.DB.Where("id = ? AND workspace_id = ?", c.Param("id"), membership.WorkspaceID).First(&webhook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Webhook not found",
+		})
+		return webhook, false
+	}
+
+	return webhook, true
+}
+
+// validateWebhookInput checks the target URL and returns the deduplicated
+// list of events. Hosts resolving to private addresses are refused by the
+// dispatcher, literal ones are rejected here already.
+func validateWebhookInput(input WebhookInput) ([]string, error) {
+	target, err := url.Parse(input.TargetURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return nil, fmt.Errorf("target_url must be an http or https URL")
+	}
+	host := target.Hostname()
+	if ip := net.ParseIP(host); strings.EqualFold(host, "localhost") || (ip != nil && !safehttp.IsPublic(ip)) {
+		return nil, fmt.Errorf("target_url must be a public address")
+	}
+
+	seen := make(map[string]bool)
+	var events []string
+	for _, event := range input.Events {
+		known := false
+		for _, e := range models.WebhookEvents {
+			if e == event {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event %q, supported events: %s", event, strings.Join(models.WebhookEvents, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func webhookResponse(webhook models.Webhook) gin.H {
+	return gin.H{
+		"id":         webhook.ID,
+		"target_url": webhook.TargetURL,
+		"events":     webhook.EventList(),
+		"active":     webhook.Active,
+		"created_at": webhook.CreatedAt,
+		"updated_at": webhook.UpdatedAt,
+	}
+}
+
+// notifyWebhooks queues a link event for the webhooks of the link's workspace.
+// Errors are logged only, webhooks must never break the request that
+// triggered them.
+func notifyWebhooks(workspaceID int, event string, data gin.H) {
+	if err := models.EnqueueWebhookEvent(workspaceID, event, data); err != nil {
+		fmt.Printf("Failed to queue webhook event %s: %v\n", event, err)
+	}
+}
+
+func linkEventData(url models.URL) gin.H {
+	return gin.H{
+		"id":           url.ID,
+		"original_url": url.OriginalURL,
+		"short_code":   url.ShortCode,
+		"short_url":    "https://electric-hideously-drake.ngrok-free.app/" + url.ShortCode,
+		"workspace_id": url.WorkspaceID,
+		"created_at":   url.CreatedAt,
+		"updated_at":   url.UpdatedAt,
+	}
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateWebhookActive(t *testing.T) {
+	setupTestDB(t)
+	workspace := models.Workspace{Name: "Personal", CreatedBy: 1, Personal: true}
+	models.CreateWorkspace(models.DB, &workspace)
+
+	r := gin.New()
+	r.POST("/api/webhooks", CreateWebhook)
+
+	tests := []struct {
+		name   string
+		active string // JSON value, empty to leave it out
+		want   bool
+	}{
+		{"inactive", "false", false},
+		{"active by default", "", true},
+		{"active", "true", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"target_url":"https://hooks.example.com/` + strings.ReplaceAll(tt.name, " ", "-") + `","events":["link.created"]`
+			if tt.active != "" {
+				body += `,"active":` + tt.active
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/webhooks", strings.NewReader(body+"}"))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", bearer(t, 1))
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+
+			var webhook models.Webhook
+			models.DB.Where("target_url LIKE ?", "%/"+strings.ReplaceAll(tt.name, " ", "-")).First(&webhook)
+			if webhook.ID == 0 || webhook.Active != tt.want {
+				t.Errorf("stored webhook %d active = %v, want %v", webhook.ID, webhook.Active, tt.want)
+			}
+		})
+	}
+
+	// Only the active webhooks get deliveries
+	if err := models.EnqueueWebhookEvent(workspace.ID, models.EventLinkCreated, gin.H{}); err != nil {
+		t.Fatal(err)
+	}
+	var deliveries int64
+	models.DB.Model(&models.WebhookDelivery{}).Count(&deliveries)
+	if deliveries != 2 {
+		t.Errorf("deliveries = %d, want 2", deliveries)
+	}
+}
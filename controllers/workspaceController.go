@@ -0,0 +1,676 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkspaceInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type InvitationInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+func CreateWorkspace(c *gin.Context) {
+	var input WorkspaceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	workspace := models.Workspace{
+		Name:      strings.TrimSpace(input.Name),
+		CreatedBy: int(userID),
+	}
+
+	if err := models.CreateWorkspace(models.DB, &workspace); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to create workspace",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Workspace created successfully",
+		"data":    workspaceResponse(workspace, models.RoleOwner),
+	})
+}
+
+// GetWorkspaces returns the workspaces the user is a member of, with the
+// user's role in each.
+func GetWorkspaces(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var rows []struct {
+		models.Workspace
+		Role string
+	}
+	if err := models.DB.Model(&models.Workspace{}).
+		Select("workspaces.*, memberships.role AS role").
+		Joins("JOIN memberships ON memberships.workspace_id = workspaces.id").
+		Where("memberships.user_id = ?", userID).
+		Order("workspaces.personal DESC, workspaces.name").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve workspaces",
+		})
+		return
+	}
+
+	workspaces := make([]gin.H, len(rows))
+	for i, row := range rows {
+		workspaces[i] = workspaceResponse(row.Workspace, row.Role)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Workspaces retrieved successfully",
+		"data":    workspaces,
+	})
+}
+
+func UpdateWorkspace(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	workspace, membership, ok := findWorkspace(c, userID, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name               *string `json:"name"`
+		AuditRetentionDays *int    `json:"audit_retention_days"`
+		TrashRetentionDays *int    `json:"trash_retention_days"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if input.Name != nil && strings.TrimSpace(*input.Name) != "" {
+		workspace.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.AuditRetentionDays != nil {
+		if *input.AuditRetentionDays < 1 || *input.AuditRetentionDays > models.MaxAuditRetentionDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": fmt.Sprintf("audit_retention_days must be between 1 and %d", models.MaxAuditRetentionDays),
+			})
+			return
+		}
+		workspace.AuditRetentionDays = *input.AuditRetentionDays
+	}
+	if input.TrashRetentionDays != nil {
+		if *input.TrashRetentionDays < 1 || *input.TrashRetentionDays > models.MaxTrashRetentionDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  false,
+				"message": fmt.Sprintf("trash_retention_days must be between 1 and %d", models.MaxTrashRetentionDays),
+			})
+			return
+		}
+		workspace.TrashRetentionDays = *input.TrashRetentionDays
+	}
+
+	if err := models.DB.Save(&workspace).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update workspace",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Workspace updated successfully",
+		"data":    workspaceResponse(workspace, membership.Role),
+	})
+}
+
+// DeleteWorkspace removes an empty team workspace. Personal workspaces can't
+// be deleted, and links have to be moved or purged from the trash first.
+func DeleteWorkspace(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := findWorkspace(c, userID, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	if workspace.Personal {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Personal workspaces cannot be deleted",
+		})
+		return
+	}
+
+	var linkCount int64
+	models.DB.Unscoped().Model(&models.URL{}).Where("workspace_id = ?", workspace.ID).Count(&linkCount)
+	if linkCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  false,
+			"message": "Workspace still has links or links in the trash",
+		})
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.Tag{}, &models.Folder{}, &models.Webhook{}, &models.Membership{}, &models.Invitation{}} {
+			if err := tx.Where("workspace_id = ?", workspace.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&workspace).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to delete workspace",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Workspace deleted successfully",
+	})
+}
+
+func GetMembers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := findWorkspace(c, userID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var members []struct {
+		UserID   int       `json:"user_id"`
+		Name     string    `json:"name"`
+		Username string    `json:"username"`
+		Email    string    `json:"email"`
+		Role     string    `json:"role"`
+		JoinedAt time.Time `json:"joined_at"`
+	}
+	if err := models.DB.Model(&models.Membership{}).
+		Select("memberships.user_id, users.name, users.username, users.email, memberships.role, memberships.created_at AS joined_at").
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.workspace_id = ?", workspace.ID).
+		Order("memberships.created_at").
+		Scan(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve members",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Members retrieved successfully",
+		"data":    members,
+	})
+}
+
+// UpdateMember changes a member's role. Admins manage editors and viewers;
+// only owners can grant or take away the owner and admin roles.
+func UpdateMember(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	workspace, membership, ok := findWorkspace(c, userID, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || !models.ValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "role must be owner, admin, editor or viewer",
+		})
+		return
+	}
+
+	member, err := models.FindMembership(workspace.ID, paramID(c, "userId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Member not found",
+		})
+		return
+	}
+
+	if !canManageRole(membership.Role, member.Role) || !canManageRole(membership.Role, input.Role) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "Only owners can manage owners and admins",
+		})
+		return
+	}
+
+	if member.Role == models.RoleOwner && input.Role != models.RoleOwner && isLastOwner(workspace.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "A workspace needs at least one owner",
+		})
+		return
+	}
+
+	member.Role = input.Role
+	if err := models.DB.Save(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to update member",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Member updated successfully",
+		"data":    member,
+	})
+}
+
+// RemoveMember removes someone from a workspace. Any member can leave on
+// their own.
+func RemoveMember(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	memberID := paramID(c, "userId")
+	minRole := models.RoleAdmin
+	if memberID == int(userID) {
+		minRole = models.RoleViewer
+	}
+
+	workspace, membership, ok := findWorkspace(c, userID, minRole)
+	if !ok {
+		return
+	}
+
+	if workspace.Personal && memberID == workspace.CreatedBy {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "You cannot leave your personal workspace",
+		})
+		return
+	}
+
+	member, err := models.FindMembership(workspace.ID, memberID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Member not found",
+		})
+		return
+	}
+
+	if memberID != int(userID) && !canManageRole(membership.Role, member.Role) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "Only owners can manage owners and admins",
+		})
+		return
+	}
+
+	if member.Role == models.RoleOwner && isLastOwner(workspace.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "A workspace needs at least one owner",
+		})
+		return
+	}
+
+	if err := models.DB.Delete(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to remove member",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Member removed successfully",
+	})
+}
+
+// CreateInvitation invites an email address into a team workspace. The token
+// is returned so the inviter can pass it on to the invitee.
+func CreateInvitation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	workspace, membership, ok := findWorkspace(c, userID, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	if workspace.Personal {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "Personal workspaces cannot be shared, create a team workspace instead",
+		})
+		return
+	}
+
+	var input InvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if !models.ValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  false,
+			"message": "role must be owner, admin, editor or viewer",
+		})
+		return
+	}
+	if !canManageRole(membership.Role, input.Role) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "Only owners can invite owners and admins",
+		})
+		return
+	}
+
+	invitation := models.Invitation{
+		WorkspaceID: workspace.ID,
+		Email:       strings.ToLower(strings.TrimSpace(input.Email)),
+		Role:        input.Role,
+		Token:       models.NewInvitationToken(),
+		InvitedBy:   int(userID),
+		ExpiresAt:   time.Now().Add(models.InvitationTTL),
+	}
+
+	if err := models.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to create invitation",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "Invitation created successfully",
+		"data": gin.H{
+			"id":           invitation.ID,
+			"workspace_id": invitation.WorkspaceID,
+			"email":        invitation.Email,
+			"role":         invitation.Role,
+			"token":        invitation.Token,
+			"expires_at":   invitation.ExpiresAt,
+		},
+	})
+}
+
+// GetInvitations lists the pending invitations of a workspace.
+func GetInvitations(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := findWorkspace(c, userID, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var invitations []models.Invitation
+	if err := models.DB.Where("workspace_id = ? AND accepted_at IS NULL AND expires_at > ?", workspace.ID, time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to retrieve invitations",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Invitations retrieved successfully",
+		"data":    invitations,
+	})
+}
+
+func DeleteInvitation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	workspace, _, ok := findWorkspace(c, userID, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	result := models.DB.Where("id = ? AND workspace_id = ? AND accepted_at IS NULL", c.Param("invitationId"), workspace.ID).Delete(&models.Invitation{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Invitation not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Invitation revoked successfully",
+	})
+}
+
+// AcceptInvitation adds the user to the workspace of an invitation sent to
+// their email address.
+func AcceptInvitation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	var invitation models.Invitation
+	if err := models.DB.Where("token = ? AND accepted_at IS NULL AND expires_at > ?", c.Param("token"), time.Now()).First(&invitation).Error; err != nil || invitation.Email != strings.ToLower(user.Email) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Invitation not found or expired",
+		})
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		invitation.AcceptedAt = &now
+		if err := tx.Save(&invitation).Error; err != nil {
+			return err
+		}
+
+		// Accepting again, e.g. with a higher role, updates the membership
+		var membership models.Membership
+		err := tx.Where("workspace_id = ? AND user_id = ?", invitation.WorkspaceID, userID).First(&membership).Error
+		if err == nil {
+			if models.RoleAtLeast(membership.Role, invitation.Role) {
+				return nil
+			}
+			return tx.Model(&membership).Update("role", invitation.Role).Error
+		}
+		return tx.Create(&models.Membership{WorkspaceID: invitation.WorkspaceID, UserID: int(userID), Role: invitation.Role}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  false,
+			"message": "Failed to accept invitation",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "Invitation accepted successfully",
+		"data": gin.H{
+			"workspace_id": invitation.WorkspaceID,
+			"role":         invitation.Role,
+		},
+	})
+}
+
+func workspaceResponse(workspace models.Workspace, role string) gin.H {
+	return gin.H{
+		"id":                   workspace.ID,
+		"name":                 workspace.Name,
+		"personal":             workspace.Personal,
+		"role":                 role,
+		"created_by":           workspace.CreatedBy,
+		"audit_retention_days": workspace.AuditRetentionDays,
+		"trash_retention_days": workspace.TrashRetentionDays,
+		"created_at":           workspace.CreatedAt,
+		"updated_at":           workspace.UpdatedAt,
+	}
+}
+
+// findWorkspace loads the workspace in the :id route parameter, checking the
+// user's role in it.
+func findWorkspace(c *gin.Context, userID uint, minRole string) (models.Workspace, models.Membership, bool) {
+	var workspace models.Workspace
+	membership, ok := authorizeWorkspace(c, userID, paramID(c, "id"), minRole)
+	if !ok {
+		return workspace, membership, false
+	}
+
+	if err := models.DB.First(&workspace, membership.WorkspaceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Workspace not found",
+		})
+		return workspace, membership, false
+	}
+
+	return workspace, membership, true
+}
+
+// requireWorkspace resolves the workspace a request works in, given by the
+// X-Workspace-ID header or the workspace_id query parameter and defaulting to
+// the user's personal workspace, and checks the user's role in it.
+func requireWorkspace(c *gin.Context, userID uint, minRole string) (models.Membership, bool) {
+	workspaceID := c.GetHeader("X-Workspace-ID")
+	if workspaceID == "" {
+		workspaceID = c.Query("workspace_id")
+	}
+
+	if workspaceID == "" {
+		id, err := models.PersonalWorkspaceID(int(userID))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  false,
+				"message": "Workspace not found",
+			})
+			return models.Membership{}, false
+		}
+		return authorizeWorkspace(c, userID, id, minRole)
+	}
+
+	id, _ := strconv.Atoi(workspaceID)
+	return authorizeWorkspace(c, userID, id, minRole)
+}
+
+// authorizeWorkspace checks that the user is a member of the workspace with at
+// least minRole, writing the error response if not.
+func authorizeWorkspace(c *gin.Context, userID uint, workspaceID int, minRole string) (models.Membership, bool) {
+	membership, err := models.FindMembership(workspaceID, int(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  false,
+			"message": "Workspace not found",
+		})
+		return membership, false
+	}
+
+	if !models.RoleAtLeast(membership.Role, minRole) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  false,
+			"message": "Your role in this workspace does not allow this action",
+		})
+		return membership, false
+	}
+
+	return membership, true
+}
+
+// canManageRole reports whether a member with the given role may assign or
+// change the target role.
+func canManageRole(role, target string) bool {
+	if role == models.RoleOwner {
+		return true
+	}
+	return role == models.RoleAdmin && !models.RoleAtLeast(target, models.RoleAdmin)
+}
+
+func isLastOwner(workspaceID int) bool {
+	var owners int64
+	models.DB.Model(&models.Membership{}).Where("workspace_id = ? AND role = ?", workspaceID, models.RoleOwner).Count(&owners)
+	return owners <= 1
+}
+
+func paramID(c *gin.Context, name string) int {
+	id, _ := strconv.Atoi(c.Param(name))
+	return id
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"backend-go/models"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWorkspacePermissions(t *testing.T) {
+	setupTestDB(t)
+	const owner, admin, editor, viewer, outsider = 1, 2, 3, 4, 5
+	for _, name := range []string{"owner", "admin", "editor", "viewer", "outsider"} {
+		models.DB.Create(&models.User{Username: name, Email: name + "@example.com"})
+	}
+	models.DB.Create(&[]models.Workspace{
+		{Name: "Team", CreatedBy: owner},
+		{Name: "Personal", CreatedBy: outsider, Personal: true},
+	})
+	models.DB.Create(&[]models.Membership{
+		{WorkspaceID: 1, UserID: owner, Role: models.RoleOwner},
+		{WorkspaceID: 1, UserID: admin, Role: models.RoleAdmin},
+		{WorkspaceID: 1, UserID: editor, Role: models.RoleEditor},
+		{WorkspaceID: 1, UserID: viewer, Role: models.RoleViewer},
+		{WorkspaceID: 2, UserID: outsider, Role: models.RoleOwner},
+	})
+	links := []models.URL{
+		{ShortCode: "team", OriginalURL: "https://team.example.com", UserID: owner, WorkspaceID: 1},
+		{ShortCode: "private", OriginalURL: "https://private.example.com", UserID: outsider, WorkspaceID: 2},
+	}
+	models.DB.Create(&links)
+	teamLink, privateLink := "/api/urls/"+strconv.Itoa(links[0].ID), "/api/urls/"+strconv.Itoa(links[1].ID)
+
+	r := gin.New()
+	r.GET("/api/urls", GetURLs)
+	r.DELETE("/api/urls/:id", DeleteURL)
+	r.GET("/api/workspaces/:id/members", GetMembers)
+	r.PUT("/api/workspaces/:id/members/:userId", UpdateMember)
+	r.DELETE("/api/workspaces/:id/members/:userId", RemoveMember)
+
+	setRole := func(role string) string { return `{"role":"` + role + `"}` }
+	member := func(workspaceID, userID int) string {
+		return "/api/workspaces/" + strconv.Itoa(workspaceID) + "/members/" + strconv.Itoa(userID)
+	}
+
+	// Steps run in order, each on the memberships the previous ones left
+	steps := []struct {
+		name      string
+		as        int
+		method    string
+		path      string
+		body      string
+		workspace string // X-Workspace-ID
+		want      int
+	}{
+		// Role escalation
+		{"editor can't change roles", editor, http.MethodPut, member(1, viewer), setRole(models.RoleEditor), "", http.StatusForbidden},
+		{"admin can't make admins", admin, http.MethodPut, member(1, editor), setRole(models.RoleAdmin), "", http.StatusForbidden},
+		{"admin can't make themselves owner", admin, http.MethodPut, member(1, admin), setRole(models.RoleOwner), "", http.StatusForbidden},
+		{"admin can't demote the owner", admin, http.MethodPut, member(1, owner), setRole(models.RoleViewer), "", http.StatusForbidden},
+		{"admin can't remove the owner", admin, http.MethodDelete, member(1, owner), "", "", http.StatusForbidden},
+		{"admin manages editors", admin, http.MethodPut, member(1, editor), setRole(models.RoleViewer), "", http.StatusOK},
+		{"invalid role", owner, http.MethodPut, member(1, editor), setRole("root"), "", http.StatusBadRequest},
+
+		// Last owner
+		{"last owner can't step down", owner, http.MethodPut, member(1, owner), setRole(models.RoleAdmin), "", http.StatusBadRequest},
+		{"last owner can't leave", owner, http.MethodDelete, member(1, owner), "", "", http.StatusBadRequest},
+		{"owner makes another owner", owner, http.MethodPut, member(1, admin), setRole(models.RoleOwner), "", http.StatusOK},
+		{"one of two owners leaves", owner, http.MethodDelete, member(1, owner), "", "", http.StatusOK},
+		{"new last owner can't step down", admin, http.MethodPut, member(1, admin), setRole(models.RoleEditor), "", http.StatusBadRequest},
+		{"personal workspace can't be left", outsider, http.MethodDelete, member(2, outsider), "", "", http.StatusBadRequest},
+
+		// Cross-workspace access
+		{"former member can't list members", owner, http.MethodGet, "/api/workspaces/1/members", "", "", http.StatusNotFound},
+		{"outsider can't change roles", outsider, http.MethodPut, member(1, viewer), setRole(models.RoleOwner), "", http.StatusNotFound},
+		{"outsider can't list links", outsider, http.MethodGet, "/api/urls", "", "1", http.StatusNotFound},
+		{"outsider can't delete links", outsider, http.MethodDelete, teamLink, "", "", http.StatusNotFound},
+		{"member can't reach a personal workspace", admin, http.MethodDelete, privateLink, "", "", http.StatusNotFound},
+		{"viewer can't delete links", viewer, http.MethodDelete, teamLink, "", "", http.StatusForbidden},
+		{"member lists links", viewer, http.MethodGet, "/api/urls", "", "1", http.StatusOK},
+		{"editor role was taken away", editor, http.MethodDelete, teamLink, "", "", http.StatusForbidden},
+	}
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", bearer(t, step.as))
+		if step.workspace != "" {
+			req.Header.Set("X-Workspace-ID", step.workspace)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != step.want {
+			t.Fatalf("%s: %s %s = %d, want %d: %s", step.name, step.method, step.path, w.Code, step.want, w.Body)
+		}
+	}
+
+	var roles []models.Membership
+	models.DB.Where("workspace_id = ?", 1).Order("user_id").Find(&roles)
+	got := map[int]string{}
+	for _, m := range roles {
+		got[m.UserID] = m.Role
+	}
+	want := map[int]string{admin: models.RoleOwner, editor: models.RoleViewer, viewer: models.RoleViewer}
+	if !maps.Equal(got, want) {
+		t.Errorf("memberships = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// ClickEvent is published every time a short link is followed.
+type ClickEvent struct {
+	ID          uint64    `json:"id"`
+	UserID      int       `json:"user_id"`
+	WorkspaceID int       `json:"workspace_id"`
+	URLID       int       `json:"url_id"`
+	ShortCode   string    `json:"short_code"`
+	ClickedAt   time.Time `json:"clicked_at"`
+	Country     string    `json:"country"`
+	Referrer    string    `json:"referrer"`
+	Device      string    `json:"device"`
+}
+
+// Subscription receives the click events of one workspace, optionally limited
+// to a single short code. C is closed when the subscription ends, either by
+// Unsubscribe or because the subscriber fell too far behind.
+type Subscription struct {
+	C           <-chan ClickEvent
+	ch          chan ClickEvent
+	workspaceID int
+	shortCode   string
+	closed      bool
+}
+
+func (s *Subscription) matches(evt ClickEvent) bool {
+	return evt.WorkspaceID == s.workspaceID && (s.shortCode == "" || evt.ShortCode == s.shortCode)
+}
+
+// Broker fans click events out to in-process subscribers. Each subscriber has
+// a bounded buffer; a subscriber that can't keep up is dropped so it can
+// reconnect and resume from the replay history instead of blocking RedirectURL.
+type Broker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	subscribers map[*Subscription]struct{}
+	history     []ClickEvent
+	historySize int
+	bufferSize  int
+}
+
+func NewBroker(bufferSize, historySize int) *Broker {
+	return &Broker{
+		subscribers: make(map[*Subscription]struct{}),
+		historySize: historySize,
+		bufferSize:  bufferSize,
+	}
+}
+
+// Clicks is the broker used by the redirect handler and the click stream.
+var Clicks = NewBroker(64, 1000)
+
+// Publish assigns the event an ID, keeps it for replay and delivers it to
+// every matching subscriber without blocking.
+func (b *Broker) Publish(evt ClickEvent) ClickEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	evt.ID = b.nextID
+
+	b.history = append(b.history, evt)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.matches(evt) {
+			continue
+		}
+		select {
+		case sub.ch <- evt:
+		default:
+			b.closeLocked(sub)
+		}
+	}
+
+	return evt
+}
+
+// Subscribe registers a subscriber. When lastEventID is set, the events after
+// it that are still in the history are returned for replay.
+func (b *Broker) Subscribe(workspaceID int, shortCode string, lastEventID uint64) (*Subscription, []ClickEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan ClickEvent, b.bufferSize)
+	sub := &Subscription{C: ch, ch: ch, workspaceID: workspaceID, shortCode: shortCode}
+	b.subscribers[sub] = struct{}{}
+
+	var replay []ClickEvent
+	if lastEventID > 0 {
+		for _, evt := range b.history {
+			if evt.ID > lastEventID && sub.matches(evt) {
+				replay = append(replay, evt)
+			}
+		}
+	}
+
+	return sub, replay
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closeLocked(sub)
+}
+
+func (b *Broker) closeLocked(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(b.subscribers, sub)
+	close(sub.ch)
+}
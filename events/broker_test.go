@@ -0,0 +1,122 @@
+package events
+
+import (
+	"slices"
+	"testing"
+)
+
+// drain returns the events waiting on a subscription and whether it is
+// still open.
+func drain(sub *Subscription) ([]string, bool) {
+	var got []string
+	for {
+		select {
+		case evt, ok := <-sub.C:
+			if !ok {
+				return got, false
+			}
+			got = append(got, evt.ShortCode)
+		default:
+			return got, true
+		}
+	}
+}
+
+func TestBrokerFilter(t *testing.T) {
+	b := NewBroker(10, 10)
+	workspace, _ := b.Subscribe(1, "", 0)
+	link, _ := b.Subscribe(1, "a", 0)
+	other, _ := b.Subscribe(2, "", 0)
+
+	b.Publish(ClickEvent{WorkspaceID: 1, ShortCode: "a"})
+	b.Publish(ClickEvent{WorkspaceID: 1, ShortCode: "b"})
+	b.Publish(ClickEvent{WorkspaceID: 2, ShortCode: "a"})
+
+	tests := []struct {
+		name string
+		sub  *Subscription
+		want []string
+	}{
+		{"whole workspace", workspace, []string{"a", "b"}},
+		{"one short code", link, []string{"a"}},
+		{"other workspace", other, []string{"a"}},
+	}
+	for _, tt := range tests {
+		got, open := drain(tt.sub)
+		if !open || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v (open %v), want %v", tt.name, got, open, tt.want)
+		}
+	}
+}
+
+func TestBrokerReplay(t *testing.T) {
+	b := NewBroker(10, 3)
+	var ids []uint64
+	for _, evt := range []ClickEvent{
+		{WorkspaceID: 1, ShortCode: "a"},
+		{WorkspaceID: 1, ShortCode: "b"},
+		{WorkspaceID: 2, ShortCode: "c"},
+		{WorkspaceID: 1, ShortCode: "d"},
+		{WorkspaceID: 1, ShortCode: "e"},
+	} {
+		ids = append(ids, b.Publish(evt).ID)
+	}
+
+	tests := []struct {
+		name        string
+		workspaceID int
+		shortCode   string
+		lastEventID uint64
+		want        []string
+	}{
+		{"new subscriber", 1, "", 0, nil},
+		{"after the last event", 1, "", ids[4], nil},
+		{"missed events", 1, "", ids[2], []string{"d", "e"}},
+		{"older than the history", 1, "", ids[0], []string{"d", "e"}}, // b fell out of the history
+		{"other workspace", 2, "", ids[0], []string{"c"}},
+		{"one short code", 1, "e", ids[0], []string{"e"}},
+	}
+	for _, tt := range tests {
+		sub, replay := b.Subscribe(tt.workspaceID, tt.shortCode, tt.lastEventID)
+		b.Unsubscribe(sub)
+		var got []string
+		for _, evt := range replay {
+			got = append(got, evt.ShortCode)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: replayed %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(2, 10)
+	slow, _ := b.Subscribe(1, "", 0)
+	fast, _ := b.Subscribe(1, "", 0)
+
+	var last ClickEvent
+	for _, code := range []string{"a", "b", "c"} {
+		last = b.Publish(ClickEvent{WorkspaceID: 1, ShortCode: code})
+		if code == "b" {
+			drain(fast)
+		}
+	}
+
+	// The slow subscriber gets what fit in its buffer, then the channel closes
+	if got, open := drain(slow); open || len(got) != 2 {
+		t.Errorf("slow subscriber got %v (open %v), want 2 events and closed", got, open)
+	}
+	if got, open := drain(fast); !open || !slices.Equal(got, []string{"c"}) {
+		t.Errorf("fast subscriber got %v (open %v), want [c] and open", got, open)
+	}
+
+	// It resumes from the history after reconnecting
+	_, replay := b.Subscribe(1, "", last.ID-1)
+	if len(replay) != 1 || replay[0].ShortCode != "c" {
+		t.Errorf("replay after reconnecting = %v", replay)
+	}
+
+	// Unsubscribing a dropped subscriber is harmless
+	b.Unsubscribe(slow)
+	b.Publish(ClickEvent{WorkspaceID: 1, ShortCode: "d"})
+}
@@ -4,7 +4,14 @@ import (
 	"backend-go/controllers"
 	"backend-go/middlewares"
 	"backend-go/models"
+	"backend-go/monitor"
+	"backend-go/redirects"
+	"backend-go/webhooks"
+	"log"
+	"os"
+	"strings"
 	"time"
+	_ "time/tzdata" // embed timezone data for analytics bucketing
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -13,11 +20,26 @@ import (
 func main() {
 	r := gin.Default()
 
+	// Proxies allowed to set X-Forwarded-For, from TRUSTED_PROXIES
+	// (comma-separated IPs or CIDRs). None by default, so the client IP is
+	// the address of the connection and can't be spoofed.
+	var trustedProxies []string
+	if env := os.Getenv("TRUSTED_PROXIES"); env != "" {
+		for _, proxy := range strings.Split(env, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				trustedProxies = append(trustedProxies, proxy)
+			}
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// CORS Middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3001", "http://127.0.0.1:3001"}, // Frontend URLs
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Last-Event-ID", "X-Workspace-ID"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -25,6 +47,67 @@ func main() {
 
 	models.ConnectDB()
 
+	// Apps allowed to open short links, from app-links.json or APP_LINKS_CONFIG
+	appLinksConfig := os.Getenv("APP_LINKS_CONFIG")
+	if appLinksConfig == "" {
+		appLinksConfig = "app-links.json"
+	}
+	if err := controllers.LoadAppLinks(appLinksConfig); err != nil {
+		log.Fatalf("Failed to load app links config: %v", err)
+	}
+
+	// Error pages of each domain, from error-pages.json or ERROR_PAGES_CONFIG
+	errorPagesConfig := os.Getenv("ERROR_PAGES_CONFIG")
+	if errorPagesConfig == "" {
+		errorPagesConfig = "error-pages.json"
+	}
+	if err := controllers.LoadErrorPages(errorPagesConfig); err != nil {
+		log.Fatalf("Failed to load error pages config: %v", err)
+	}
+
+	// Users allowed to review abuse reports, from ADMIN_EMAILS
+	// (comma-separated). Admins no longer listed lose the role.
+	if err := models.SyncAdmins(strings.Split(os.Getenv("ADMIN_EMAILS"), ",")); err != nil {
+		log.Fatalf("Failed to update admins: %v", err)
+	}
+
+	// Send queued webhook deliveries in the background
+	webhooks.NewDispatcher().Start()
+
+	// Hosts serving our short links, from SHORT_DOMAINS (comma-separated).
+	// Redirect chains through them are followed by looking the links up.
+	shortDomains := redirects.DefaultOwnHosts
+	if env := os.Getenv("SHORT_DOMAINS"); env != "" {
+		shortDomains = strings.Split(env, ",")
+	}
+	resolver := redirects.NewResolver(shortDomains)
+	controllers.Resolver = resolver
+
+	// Check the destinations of the active links in the background
+	linkMonitor := monitor.NewMonitor()
+	linkMonitor.Resolver = resolver
+	linkMonitor.Start()
+
+	// Drop audit events past their retention once a day
+	go func() {
+		for {
+			if _, err := models.PurgeAuditEvents(); err != nil {
+				log.Printf("Failed to purge audit events: %v", err)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+
+	// Purge links that stayed in the trash past their retention once a day
+	go func() {
+		for {
+			if _, err := models.PurgeTrashedURLs(); err != nil {
+				log.Printf("Failed to purge trashed links: %v", err)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
@@ -37,22 +120,108 @@ func main() {
 		api.POST("/register", controllers.Register)
 		api.POST("/login", controllers.Login)
 
+		// Authenticates on its own, browsers open it with a stream token
+		api.GET("/stream/clicks", controllers.StreamClicks)
+
 		protected := api.Group("/")
 		protected.Use(middlewares.AuthMiddleware())
 		{
 			protected.GET("/profile", controllers.GetProfile)
+			protected.PUT("/profile", controllers.UpdateProfile)
+			protected.GET("/profile/error-pages", controllers.GetErrorPages)
+			protected.PUT("/profile/error-pages/:kind", controllers.SetErrorPage)
+			protected.DELETE("/profile/error-pages/:kind", controllers.DeleteErrorPage)
 			protected.POST("/shorten", controllers.CreateShortURL)
 			protected.GET("/urls", controllers.GetURLs)
 			protected.GET("/stats/:shortCode", controllers.GetURLStats)
 			protected.PUT("/urls/:id", controllers.UpdateURL)
 			protected.DELETE("/urls/:id", controllers.DeleteURL)
+			protected.POST("/urls/bulk", controllers.BulkUpdateURLs)
+			protected.GET("/urls/:id/clicks", controllers.GetURLClicks)
+			protected.GET("/urls/:id/health", controllers.GetURLHealth)
+			protected.GET("/urls/:id/revisions", controllers.GetURLRevisions)
+			protected.POST("/urls/:id/revisions/:version/rollback", controllers.RollbackURL)
+			protected.GET("/trash", controllers.GetTrash)
+			protected.POST("/trash/:id/restore", controllers.RestoreURL)
+			protected.DELETE("/trash/:id", controllers.PurgeURL)
 			protected.POST("/change-password", controllers.ChangePassword)
 			protected.GET("/analytics", controllers.GetAnalytics)
+			protected.POST("/stream/token", controllers.CreateStreamToken)
+			protected.POST("/conversions", controllers.CreateConversion)
+
+			protected.GET("/pages", controllers.GetPages)
+			protected.POST("/pages", controllers.CreatePage)
+			protected.GET("/pages/:id", controllers.GetPage)
+			protected.PUT("/pages/:id", controllers.UpdatePage)
+			protected.DELETE("/pages/:id", controllers.DeletePage)
+			protected.POST("/pages/:id/blocks", controllers.CreateBlock)
+			protected.PUT("/pages/:id/blocks/order", controllers.ReorderBlocks)
+			protected.PUT("/pages/:id/blocks/:blockId", controllers.UpdateBlock)
+			protected.DELETE("/pages/:id/blocks/:blockId", controllers.DeleteBlock)
+
+			protected.POST("/tags", controllers.CreateTag)
+			protected.GET("/tags", controllers.GetTags)
+			protected.PUT("/tags/:id", controllers.UpdateTag)
+			protected.DELETE("/tags/:id", controllers.DeleteTag)
+
+			protected.POST("/folders", controllers.CreateFolder)
+			protected.GET("/folders", controllers.GetFolders)
+			protected.PUT("/folders/:id", controllers.UpdateFolder)
+			protected.DELETE("/folders/:id", controllers.DeleteFolder)
+
+			protected.POST("/workspaces", controllers.CreateWorkspace)
+			protected.GET("/workspaces", controllers.GetWorkspaces)
+			protected.PUT("/workspaces/:id", controllers.UpdateWorkspace)
+			protected.DELETE("/workspaces/:id", controllers.DeleteWorkspace)
+			protected.GET("/workspaces/:id/members", controllers.GetMembers)
+			protected.PUT("/workspaces/:id/members/:userId", controllers.UpdateMember)
+			protected.DELETE("/workspaces/:id/members/:userId", controllers.RemoveMember)
+			protected.POST("/workspaces/:id/invitations", controllers.CreateInvitation)
+			protected.GET("/workspaces/:id/invitations", controllers.GetInvitations)
+			protected.DELETE("/workspaces/:id/invitations/:invitationId", controllers.DeleteInvitation)
+			protected.POST("/invitations/:token/accept", controllers.AcceptInvitation)
+
+			protected.GET("/audit", controllers.GetAuditEvents)
+
+			protected.POST("/transfers", controllers.CreateTransfer)
+			protected.GET("/transfers", controllers.GetTransfers)
+			protected.POST("/transfers/:id/accept", controllers.AcceptTransfer)
+			protected.POST("/transfers/:id/decline", controllers.DeclineTransfer)
+			protected.POST("/transfers/:id/cancel", controllers.CancelTransfer)
+
+			protected.POST("/webhooks", controllers.CreateWebhook)
+			protected.GET("/webhooks", controllers.GetWebhooks)
+			protected.PUT("/webhooks/:id", controllers.UpdateWebhook)
+			protected.DELETE("/webhooks/:id", controllers.DeleteWebhook)
+			protected.GET("/webhooks/:id/deliveries", controllers.GetWebhookDeliveries)
+			protected.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", controllers.RedeliverWebhook)
+
+			protected.GET("/admin/reports", controllers.GetReportQueue)
+			protected.POST("/admin/urls/:id/block", controllers.BlockURL)
+			protected.POST("/admin/urls/:id/unblock", controllers.UnblockURL)
 		}
 	}
 
+	// App association files for universal links and Android App Links
+	r.GET("/.well-known/apple-app-site-association", controllers.AppleAppSiteAssociation)
+	r.GET("/apple-app-site-association", controllers.AppleAppSiteAssociation)
+	r.GET("/.well-known/assetlinks.json", controllers.AssetLinks)
+
+	// Conversion pixel, loaded by the destination site
+	r.GET("/t/pixel.gif", controllers.ConversionPixel)
+
+	// Abuse reports, at most 10 per hour from one IP
+	r.GET("/report/*shortCode", controllers.ReportForm)
+	r.POST("/report/*shortCode", middlewares.RateLimit(10, time.Hour), controllers.CreateReport)
+
+	// Link-in-bio pages
+	r.GET("/@:username", controllers.RenderPage)
+	r.GET("/@:username/:slug", controllers.RenderPage)
+	r.GET("/@:username/b/:blockId", controllers.PageBlockClick)
+
 	// Redirect route
 	r.GET("/:shortCode", controllers.RedirectURL)
+	r.GET("/:shortCode/*path", controllers.RedirectURL)
 
 	r.Run(":3000") // listen and serve on 0.0.0.0:3000 (for windows "localhost:3000")
 }
@@ -32,6 +32,13 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Only login tokens carry user_id; stream tokens are signed with the
+		// same key but must not open the rest of the API
+		if claims, ok := token.Claims.(jwt.MapClaims); !ok || claims["user_id"] == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit allows each client IP limit requests per window. The client IP
+// is the connection's address unless it comes from a trusted proxy. Counts
+// are kept in memory, so they start over when the server restarts.
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	type counter struct {
+		count   int
+		resetAt time.Time
+	}
+	var mu sync.Mutex
+	counters := map[string]*counter{}
+	nextSweep := time.Now().Add(window)
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		// Forget the clients whose window is over, now and then
+		if now.After(nextSweep) {
+			for key, entry := range counters {
+				if now.After(entry.resetAt) {
+					delete(counters, key)
+				}
+			}
+			nextSweep = now.Add(window)
+		}
+		entry, ok := counters[ip]
+		if !ok || now.After(entry.resetAt) {
+			entry = &counter{resetAt: now.Add(window)}
+			counters[ip] = entry
+		}
+		entry.count++
+		allowed := entry.count <= limit
+		retryAfter := entry.resetAt.Sub(now)
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"status":  false,
+				"message": "Too many requests, try again later",
+			})
+			return
+		}
+		c.Next()
+	}
+}
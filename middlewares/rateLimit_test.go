@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRateLimitedRouter(t *testing.T, limit int, window time.Duration, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/", RateLimit(limit, window), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func get(r *gin.Engine, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	type request struct {
+		remoteAddr   string
+		forwardedFor string
+		want         int
+	}
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		requests       []request
+	}{
+		{
+			name: "allows up to the limit",
+			requests: []request{
+				{"1.1.1.1:1000", "", http.StatusOK},
+				{"1.1.1.1:1001", "", http.StatusOK},
+				{"1.1.1.1:1002", "", http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "counts each client on its own",
+			requests: []request{
+				{"1.1.1.1:1000", "", http.StatusOK},
+				{"1.1.1.1:1000", "", http.StatusOK},
+				{"2.2.2.2:1000", "", http.StatusOK},
+				{"1.1.1.1:1000", "", http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "ignores X-Forwarded-For from untrusted clients",
+			requests: []request{
+				{"1.1.1.1:1000", "10.0.0.1", http.StatusOK},
+				{"1.1.1.1:1000", "10.0.0.2", http.StatusOK},
+				{"1.1.1.1:1000", "10.0.0.3", http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:           "uses X-Forwarded-For from trusted proxies",
+			trustedProxies: []string{"192.168.0.0/16"},
+			requests: []request{
+				{"192.168.1.1:1000", "1.1.1.1", http.StatusOK},
+				{"192.168.1.1:1000", "1.1.1.1", http.StatusOK},
+				{"192.168.1.1:1000", "2.2.2.2", http.StatusOK},
+				{"192.168.1.2:1000", "1.1.1.1", http.StatusTooManyRequests},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRateLimitedRouter(t, 2, time.Hour, tt.trustedProxies)
+			for i, req := range tt.requests {
+				w := get(r, req.remoteAddr, req.forwardedFor)
+				if w.Code != req.want {
+					t.Fatalf("request %d: status = %d, want %d", i, w.Code, req.want)
+				}
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+					t.Errorf("request %d: missing Retry-After", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitWindowReset(t *testing.T) {
+	r := newRateLimitedRouter(t, 1, 50*time.Millisecond, nil)
+	if w := get(r, "1.1.1.1:1000", ""); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d", w.Code)
+	}
+	if w := get(r, "1.1.1.1:1000", ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status = %d", w.Code)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if w := get(r, "1.1.1.1:1000", ""); w.Code != http.StatusOK {
+		t.Fatalf("after the window: status = %d", w.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+)
+
+// AppLink is how a link opens in a mobile app. Visitors on the platform get
+// a bounce page that tries the app and falls back to the store, then to the
+// web destination.
+type AppLink struct {
+	AppURI   string `json:"app_uri"`   // Custom scheme URI, e.g. myapp://item/42
+	LinkURL  string `json:"link_url"`  // Universal link (iOS) or App Link (Android)
+	StoreURL string `json:"store_url"` // App Store or Play Store page
+}
+
+// Schemes an app URI can't use, since they would run in the bounce page
+var blockedAppSchemes = []string{"javascript", "data", "vbscript", "file", "about", "blob"}
+
+// IsSet reports whether the app link opens an app at all.
+func (a AppLink) IsSet() bool {
+	return a.AppURI != "" || a.LinkURL != ""
+}
+
+// Validate checks the URIs of the app link.
+func (a AppLink) Validate() error {
+	if a.AppURI != "" {
+		parsed, err := neturl.Parse(a.AppURI)
+		if err != nil || parsed.Scheme == "" {
+			return fmt.Errorf("app_uri must be an absolute URI such as myapp://path")
+		}
+		for _, scheme := range blockedAppSchemes {
+			if strings.EqualFold(parsed.Scheme, scheme) {
+				return fmt.Errorf("app_uri can't use the %s scheme", parsed.Scheme)
+			}
+		}
+	}
+	for name, value := range map[string]string{"link_url": a.LinkURL, "store_url": a.StoreURL} {
+		if value == "" {
+			continue
+		}
+		if parsed, err := neturl.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("%s must be an http or https URL", name)
+		}
+	}
+	if a.StoreURL != "" && !a.IsSet() {
+		return fmt.Errorf("store_url needs an app_uri or link_url")
+	}
+	return nil
+}
+
+// AppLinkFor returns the app link of the link for a visitor's OS, or nil when
+// there is none.
+func (u URL) AppLinkFor(os string) *AppLink {
+	switch {
+	case os == "ios" && u.IOS.IsSet():
+		return &u.IOS
+	case os == "android" && u.Android.IsSet():
+		return &u.Android
+	}
+	return nil
+}
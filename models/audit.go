@@ -0,0 +1,133 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditUserRegistered  = "user.registered"
+	AuditLoginSucceeded  = "user.login_succeeded"
+	AuditLoginFailed     = "user.login_failed"
+	AuditPasswordChanged = "user.password_changed"
+	AuditURLCreated      = "url.created"
+	AuditURLUpdated      = "url.updated"
+	AuditURLDeleted      = "url.deleted"
+	AuditURLTransferred  = "url.transferred"
+	AuditURLRestored     = "url.restored"
+	AuditURLPurged       = "url.purged"
+	AuditURLBlocked      = "url.blocked"
+	AuditURLUnblocked    = "url.unblocked"
+
+	AuditTransferDeclined  = "transfer.declined"
+	AuditTransferCancelled = "transfer.cancelled"
+)
+
+// Audit events are kept for the retention of their workspace. Events outside
+// any workspace, such as failed logins for unknown emails, use the default.
+const (
+	DefaultAuditRetentionDays = 365
+	MaxAuditRetentionDays     = 3650
+)
+
+// AuditChange is the value of one field before and after an action.
+type AuditChange struct {
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// AuditChanges maps field names to their change. It is stored as JSON.
+type AuditChanges map[string]AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case []byte:
+		return json.Unmarshal(v, c)
+	}
+	return fmt.Errorf("unsupported audit changes type %T", value)
+}
+
+func (AuditChanges) GormDataType() string {
+	return "text"
+}
+
+// DiffAudit returns the fields whose values differ between before and after.
+// A nil map stands for a record that didn't exist, as on create or delete.
+func DiffAudit(before, after map[string]interface{}) AuditChanges {
+	changes := AuditChanges{}
+	for field, value := range after {
+		if old := before[field]; !reflect.DeepEqual(old, value) {
+			changes[field] = AuditChange{From: old, To: value}
+		}
+	}
+	for field, value := range before {
+		if _, ok := after[field]; !ok && value != nil {
+			changes[field] = AuditChange{From: value}
+		}
+	}
+	return changes
+}
+
+// AuditEvent is one entry of the append-only audit log. Rows are never
+// updated; they are only deleted once past their retention.
+type AuditEvent struct {
+	ID          int          `json:"id" gorm:"primary_key"`
+	ActorID     *int         `json:"actor_id" gorm:"index"` // Nil when nobody is signed in, e.g. failed logins
+	WorkspaceID *int         `json:"workspace_id" gorm:"index"`
+	Action      string       `json:"action" gorm:"not null;index"`
+	TargetType  string       `json:"target_type" gorm:"index:idx_audit_target"`
+	TargetID    int          `json:"target_id" gorm:"index:idx_audit_target"`
+	Changes     AuditChanges `json:"changes"`
+	Details     string       `json:"details"`
+	IP          string       `json:"ip"`
+	UserAgent   string       `json:"user_agent"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"index"`
+}
+
+// RecordAuditEvent appends an event to the audit log.
+func RecordAuditEvent(event *AuditEvent) error {
+	return DB.Create(event).Error
+}
+
+// PurgeAuditEvents deletes the events older than the retention of their
+// workspace and returns how many were removed.
+func PurgeAuditEvents() (int64, error) {
+	now := time.Now()
+
+	var retentions []int
+	if err := DB.Model(&Workspace{}).Distinct().Pluck("audit_retention_days", &retentions).Error; err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, days := range retentions {
+		result := DB.Where("workspace_id IN (SELECT id FROM workspaces WHERE audit_retention_days = ?)", days).
+			Where("created_at < ?", now.AddDate(0, 0, -days)).
+			Delete(&AuditEvent{})
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged += result.RowsAffected
+	}
+
+	result := DB.Where("workspace_id IS NULL AND created_at < ?", now.AddDate(0, 0, -DefaultAuditRetentionDays)).Delete(&AuditEvent{})
+	purged += result.RowsAffected
+	return purged, result.Error
+}
@@ -2,12 +2,41 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Click struct {
-	ID        int       `json:"id" gorm:"primary_key"`
-	URLID     int       `json:"url_id" gorm:"not null"`
-	ClickedAt time.Time `json:"clicked_at" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int       `json:"id" gorm:"primary_key"`
+	URLID       int       `json:"url_id" gorm:"not null"`
+	ClickedAt   time.Time `json:"clicked_at" gorm:"not null"`
+	CID         string    `json:"cid" gorm:"column:cid;index"` // Public click id, passed on as sl_cid to attribute conversions
+	Country     string    `json:"country"`
+	Referrer    string    `json:"referrer"`
+	Device      string    `json:"device"`
+	RuleID      *int      `json:"rule_id" gorm:"index"`      // Routing rule that picked the destination, if any
+	VariantID   *int      `json:"variant_id" gorm:"index"`   // A/B variant the visitor was sent to, if any
+	BlockID     *int      `json:"block_id" gorm:"index"`     // Link-in-bio block the visitor came from, if any
+	VisitorHash string    `json:"visitor_hash" gorm:"index"` // Salted hash of IP and user agent; the raw IP is never stored
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RecordClick saves a click and updates the hourly and daily rollups and the
+// link's click count in one transaction, so analytics never sees a click
+// that isn't counted and the count always matches the rollups.
+func RecordClick(click *Click) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(click).Error; err != nil {
+			return err
+		}
+		if err := incrementRollups(tx, click); err != nil {
+			return err
+		}
+		// Not through Updates, a click isn't an edit of the link
+		return tx.Model(&URL{}).Unscoped().Where("id = ?", click.URLID).UpdateColumns(map[string]interface{}{
+			"click_count":     gorm.Expr("click_count + 1"),
+			"last_clicked_at": click.ClickedAt,
+		}).Error
+	})
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClickCountMatchesRollups(t *testing.T) {
+	setupTestDB(t)
+	urls := []URL{
+		{ShortCode: "a", OriginalURL: "https://a.example.com"},
+		{ShortCode: "b", OriginalURL: "https://b.example.com"},
+	}
+	DB.Create(&urls)
+
+	start := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		click := Click{URLID: urls[i%2].ID, ClickedAt: start.Add(time.Duration(i) * 7 * time.Hour)}
+		if err := RecordClick(&click); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(t *testing.T) {
+		t.Helper()
+		for _, url := range urls {
+			var stored URL
+			DB.First(&stored, url.ID)
+			var hourly, daily int64
+			DB.Model(&HourlyClickRollup{}).Where("url_id = ?", url.ID).Select("COALESCE(SUM(clicks), 0)").Scan(&hourly)
+			DB.Model(&DailyClickRollup{}).Where("url_id = ?", url.ID).Select("COALESCE(SUM(clicks), 0)").Scan(&daily)
+			if int64(stored.ClickCount) != hourly || hourly != daily {
+				t.Errorf("link %s: click_count %d, hourly %d, daily %d", url.ShortCode, stored.ClickCount, hourly, daily)
+			}
+		}
+	}
+
+	t.Run("recorded", func(t *testing.T) {
+		check(t)
+		var stored URL
+		DB.First(&stored, urls[0].ID)
+		if stored.ClickCount != 3 || stored.LastClickedAt == nil {
+			t.Errorf("click_count = %d, last_clicked_at = %v", stored.ClickCount, stored.LastClickedAt)
+		}
+	})
+
+	t.Run("rebuilt", func(t *testing.T) {
+		DB.Model(&URL{}).Where("id = ?", urls[1].ID).UpdateColumn("click_count", 42)
+		processed, err := RebuildClickRollups(DB)
+		if err != nil || processed != 5 {
+			t.Fatalf("RebuildClickRollups() = %d, %v", processed, err)
+		}
+		check(t)
+	})
+}
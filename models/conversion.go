@@ -0,0 +1,77 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Conversion sources
+const (
+	ConversionPixel = "pixel"
+	ConversionAPI   = "api"
+)
+
+// DefaultConversionGoal is the goal of conversions reported without one.
+const DefaultConversionGoal = "conversion"
+
+// AttributionWindow is how long after a click a conversion is credited to it.
+const AttributionWindow = 30 * 24 * time.Hour
+
+var (
+	ErrClickNotFound      = errors.New("click not found")
+	ErrOutsideAttribution = errors.New("click is outside the attribution window")
+)
+
+// Conversion is a goal reached by a visitor after clicking a link, tied back
+// to the click through its click id. A click converts at most once per goal
+// and order id.
+type Conversion struct {
+	ID        int       `json:"id" gorm:"primary_key"`
+	ClickID   int       `json:"click_id" gorm:"not null;uniqueIndex:idx_conversion_click_goal_order"`
+	URLID     int       `json:"url_id" gorm:"not null;index"`
+	VariantID *int      `json:"variant_id" gorm:"index"`
+	Goal      string    `json:"goal" gorm:"not null;uniqueIndex:idx_conversion_click_goal_order"`
+	OrderID   string    `json:"order_id" gorm:"not null;default:'';uniqueIndex:idx_conversion_click_goal_order"`
+	Revenue   float64   `json:"revenue" gorm:"not null;default:0"`
+	Currency  string    `json:"currency"`
+	Source    string    `json:"source" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// NewClickID returns a random, unguessable click id for the sl_cid parameter.
+func NewClickID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// FindClickByCID returns the click with the given public click id.
+func FindClickByCID(cid string) (Click, error) {
+	var click Click
+	if cid == "" || DB.Where("cid = ?", cid).First(&click).Error != nil {
+		return click, ErrClickNotFound
+	}
+	return click, nil
+}
+
+// RecordConversion credits a conversion to a click. Reporting the same goal
+// and order id again returns the conversion recorded the first time; created
+// tells whether it is new.
+func RecordConversion(click Click, conversion *Conversion) (created bool, err error) {
+	if time.Since(click.ClickedAt) > AttributionWindow {
+		return false, ErrOutsideAttribution
+	}
+
+	if conversion.Goal == "" {
+		conversion.Goal = DefaultConversionGoal
+	}
+	conversion.ClickID = click.ID
+	conversion.URLID = click.URLID
+	conversion.VariantID = click.VariantID
+
+	result := DB.Where("click_id = ? AND goal = ? AND order_id = ?", click.ID, conversion.Goal, conversion.OrderID).
+		FirstOrCreate(conversion)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"sync"
+	"text/template/parse"
+	"time"
+)
+
+// Kinds of error page
+const (
+	ErrorPageNotFound = "not_found"
+	ErrorPageExpired  = "expired"
+	ErrorPageDisabled = "disabled"
+)
+
+var ErrorPageKinds = []string{ErrorPageNotFound, ErrorPageExpired, ErrorPageDisabled}
+
+// MaxErrorTemplateSize limits custom error page templates.
+const MaxErrorTemplateSize = 64 * 1024
+
+// ErrorPage is a user's own page for visitors of their links that can't be
+// followed: either a redirect or an HTML template.
+type ErrorPage struct {
+	ID          int       `json:"id" gorm:"primary_key"`
+	UserID      int       `json:"user_id" gorm:"not null;uniqueIndex:idx_error_page_user_kind"`
+	Kind        string    `json:"kind" gorm:"not null;uniqueIndex:idx_error_page_user_kind"`
+	RedirectURL string    `json:"redirect_url"`
+	Template    string    `json:"template"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ErrorPageData is what error page templates can show.
+type ErrorPageData struct {
+	Kind      string
+	Status    int
+	Message   string
+	ShortCode string
+}
+
+// ValidErrorPageKind reports whether kind is one of ErrorPageKinds.
+func ValidErrorPageKind(kind string) bool {
+	for _, k := range ErrorPageKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxErrorPageSize limits what an error page template may render.
+const MaxErrorPageSize = 256 * 1024
+
+// Functions error page templates may call. Anything looping or building
+// large strings, like printf, is left out.
+var errorTemplateFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+	"html": true, "js": true, "urlquery": true,
+}
+
+// ParseErrorTemplate parses an error page template and renders it once with
+// sample data, so templates that can't render are rejected when saved.
+// Templates may only show fields, with if and with; loops, nested templates
+// and most functions are rejected so a page can't run for long.
+func ParseErrorTemplate(text string) (*template.Template, error) {
+	if len(text) > MaxErrorTemplateSize {
+		return nil, fmt.Errorf("template must be at most %d bytes", MaxErrorTemplateSize)
+	}
+	tmpl, err := template.New("error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, errors.New("define and block are not allowed")
+	}
+	if tmpl.Tree != nil {
+		if err := checkErrorTemplateNode(tmpl.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+	sample := ErrorPageData{Kind: ErrorPageNotFound, Status: 404, Message: "Short URL not found", ShortCode: "abc"}
+	if _, err := RenderErrorTemplate(tmpl, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func checkErrorTemplateNode(node parse.Node) error {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkErrorTemplateNode(child); err != nil {
+				return err
+			}
+		}
+	case *parse.TextNode, *parse.CommentNode, *parse.DotNode, *parse.FieldNode, *parse.VariableNode,
+		*parse.BoolNode, *parse.NumberNode, *parse.StringNode, *parse.NilNode:
+	case *parse.ActionNode:
+		return checkErrorTemplateNode(n.Pipe)
+	case *parse.IfNode:
+		return checkErrorTemplateBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkErrorTemplateBranch(&n.BranchNode)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkErrorTemplateNode(cmd); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkErrorTemplateNode(arg); err != nil {
+				return err
+			}
+		}
+	case *parse.IdentifierNode:
+		if !errorTemplateFuncs[n.Ident] {
+			return fmt.Errorf("function %q is not allowed", n.Ident)
+		}
+	case *parse.ChainNode:
+		return checkErrorTemplateNode(n.Node)
+	default:
+		return fmt.Errorf("%s is not allowed", node)
+	}
+	return nil
+}
+
+func checkErrorTemplateBranch(n *parse.BranchNode) error {
+	for _, child := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if err := checkErrorTemplateNode(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrTemplateTooLarge is returned when an error page renders more than
+// MaxErrorPageSize bytes.
+var ErrTemplateTooLarge = errors.New("template output is too large")
+
+// RenderErrorTemplate renders an error page, up to MaxErrorPageSize bytes.
+func RenderErrorTemplate(tmpl *template.Template, data ErrorPageData) ([]byte, error) {
+	w := &limitedBuffer{limit: MaxErrorPageSize}
+	if err := tmpl.Execute(w, data); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *limitedBuffer) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, ErrTemplateTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+type cachedErrorTemplate struct {
+	updatedAt time.Time
+	tmpl      *template.Template
+}
+
+var (
+	errorTemplatesMu sync.Mutex
+	errorTemplates   = map[int]cachedErrorTemplate{}
+)
+
+// ParsedTemplate returns the page's parsed template, parsed again only when
+// the page was changed since.
+func (p *ErrorPage) ParsedTemplate() (*template.Template, error) {
+	errorTemplatesMu.Lock()
+	cached, ok := errorTemplates[p.ID]
+	errorTemplatesMu.Unlock()
+	if ok && cached.updatedAt.Equal(p.UpdatedAt) {
+		return cached.tmpl, nil
+	}
+
+	tmpl, err := ParseErrorTemplate(p.Template)
+	if err != nil {
+		return nil, err
+	}
+	errorTemplatesMu.Lock()
+	errorTemplates[p.ID] = cachedErrorTemplate{updatedAt: p.UpdatedAt, tmpl: tmpl}
+	errorTemplatesMu.Unlock()
+	return tmpl, nil
+}
+
+// ForgetErrorTemplate drops the cached template of a deleted page.
+func ForgetErrorTemplate(id int) {
+	errorTemplatesMu.Lock()
+	delete(errorTemplates, id)
+	errorTemplatesMu.Unlock()
+}
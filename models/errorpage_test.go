@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr bool
+	}{
+		{"plain text", "<h1>Oops</h1>", false},
+		{"fields", "<h1>{{.Status}}</h1><p>{{.Message}}</p>{{.ShortCode}}", false},
+		{"if and with", `{{if eq .Kind "expired"}}gone{{else}}missing{{end}}{{with .ShortCode}}{{.}}{{end}}`, false},
+		{"variables and pipes", `{{$m := .Message}}{{$m | html}}{{len .ShortCode}}`, false},
+		{"comments", "{{/* note */}}ok", false},
+		{"range over an integer", "{{range 1000000000}}x{{end}}", true},
+		{"nested ranges", "{{range 100000}}{{range 100000}}x{{end}}{{end}}", true},
+		{"define", `{{define "x"}}a{{end}}b`, true},
+		{"template", `{{template "error" .}}`, true},
+		{"block", `{{block "x" .}}a{{end}}`, true},
+		{"printf", `{{printf "%0999999999d" 1}}`, true},
+		{"call", `{{call .Message}}`, true},
+		{"unknown field", "{{.Nope}}", true},
+		{"syntax error", "{{if}}", true},
+		{"too large", strings.Repeat("a", MaxErrorTemplateSize+1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseErrorTemplate(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseErrorTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderErrorTemplateLimit(t *testing.T) {
+	tmpl, err := ParseErrorTemplate("{{.Message}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := RenderErrorTemplate(tmpl, ErrorPageData{Message: "gone"})
+	if err != nil || string(body) != "gone" {
+		t.Fatalf("RenderErrorTemplate() = %q, %v", body, err)
+	}
+
+	_, err = RenderErrorTemplate(tmpl, ErrorPageData{Message: strings.Repeat("a", MaxErrorPageSize+1)})
+	if !errors.Is(err, ErrTemplateTooLarge) {
+		t.Fatalf("RenderErrorTemplate() error = %v, want ErrTemplateTooLarge", err)
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Folder groups links. Folders can be nested; a link without a folder lives at
+// the root.
+type Folder struct {
+	ID          int       `json:"id" gorm:"primary_key"`
+	UserID      int       `json:"user_id" gorm:"not null;index"`
+	WorkspaceID int       `json:"workspace_id" gorm:"index"`
+	Name        string    `json:"name" gorm:"not null"`
+	ParentID    *int      `json:"parent_id" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FolderWithDescendants returns the ID of the folder and of all folders nested
+// below it.
+func FolderWithDescendants(workspaceID int, folderID int) []int {
+	var folders []Folder
+	DB.Select("id", "parent_id").Where("workspace_id = ?", workspaceID).Find(&folders)
+
+	children := make(map[int][]int)
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder.ID)
+		}
+	}
+
+	ids := []int{folderID}
+	seen := map[int]bool{folderID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
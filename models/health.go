@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Health of a link's destination
+const (
+	HealthUnknown = "unknown"
+	HealthHealthy = "healthy"
+	HealthBroken  = "broken"
+)
+
+const (
+	// BrokenAfterFailures is how many failed checks in a row flag a link, so
+	// a single hiccup of the destination doesn't.
+	BrokenAfterFailures = 2
+	// HealthCheckRetention is how long the check history is kept.
+	HealthCheckRetention = 30 * 24 * time.Hour
+)
+
+// HealthCheck is one probe of a link's destination.
+type HealthCheck struct {
+	ID             int       `json:"id" gorm:"primary_key"`
+	URLID          int       `json:"url_id" gorm:"not null;index"`
+	StatusCode     int       `json:"status_code"` // 0 when the destination was unreachable
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	Error          string    `json:"error"`
+	FinalURL       string    `json:"final_url"` // Where the redirects of the destination lead
+	RedirectHops   int       `json:"redirect_hops"`
+	Healthy        bool      `json:"healthy"`
+	CheckedAt      time.Time `json:"checked_at" gorm:"index"`
+}
+
+// ErrLinkChanged is returned when a link got a new destination or was
+// deleted while its old destination was being checked.
+var ErrLinkChanged = errors.New("the link changed during the check")
+
+// RecordHealthCheck stores a check of url's destination and updates the
+// health of the link. It reports the link's health before the check, so
+// callers can tell when it changed. Checks of a destination the link no
+// longer has are dropped with ErrLinkChanged.
+func RecordHealthCheck(url *URL, check *HealthCheck) (previous string, err error) {
+	previous = url.HealthStatus
+	check.URLID = url.ID
+
+	failures := 0
+	status := HealthHealthy
+	if !check.Healthy {
+		failures = url.HealthFailures + 1
+		status = url.HealthStatus
+		if failures >= BrokenAfterFailures {
+			status = HealthBroken
+		}
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// Not through Save, so UpdatedAt keeps meaning the last edit
+		result := tx.Model(&URL{}).Where("id = ? AND original_url = ?", url.ID, url.OriginalURL).UpdateColumns(map[string]interface{}{
+			"health_status":      status,
+			"health_failures":    failures,
+			"health_status_code": check.StatusCode,
+			"health_checked_at":  check.CheckedAt,
+			"final_url":          check.FinalURL,
+			"redirect_hops":      check.RedirectHops,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrLinkChanged
+		}
+		return tx.Create(check).Error
+	})
+	if err != nil {
+		return previous, err
+	}
+
+	url.HealthStatus = status
+	url.HealthFailures = failures
+	url.HealthStatusCode = check.StatusCode
+	url.HealthCheckedAt = &check.CheckedAt
+	url.FinalURL = check.FinalURL
+	url.RedirectHops = check.RedirectHops
+	return previous, nil
+}
+
+// ResetHealth forgets the health of the old destination when a link gets a
+// new one.
+func (u *URL) ResetHealth() {
+	u.HealthStatus = HealthUnknown
+	u.HealthFailures = 0
+	u.HealthStatusCode = 0
+	u.HealthCheckedAt = nil
+	u.FinalURL = ""
+	u.RedirectHops = 0
+}
+
+// PurgeHealthChecks deletes the checks older than HealthCheckRetention.
+func PurgeHealthChecks() (int64, error) {
+	result := DB.Where("checked_at < ?", time.Now().Add(-HealthCheckRetention)).Delete(&HealthCheck{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordHealthCheck(t *testing.T) {
+	setupTestDB(t)
+	url := URL{ShortCode: "health", OriginalURL: "https://old.example.com", HealthStatus: HealthHealthy}
+	if err := DB.Create(&url).Error; err != nil {
+		t.Fatal(err)
+	}
+	probed := url // The monitor's copy, loaded before the destination changes
+
+	steps := []struct {
+		name         string
+		healthy      bool
+		edit         string // New destination saved before the check is recorded
+		wantErr      error
+		wantPrevious string
+		wantStatus   string
+		wantChecks   int64
+	}{
+		{name: "first failure", wantPrevious: HealthHealthy, wantStatus: HealthHealthy, wantChecks: 1},
+		{name: "second failure", wantPrevious: HealthHealthy, wantStatus: HealthBroken, wantChecks: 2},
+		{name: "recovery", healthy: true, wantPrevious: HealthBroken, wantStatus: HealthHealthy, wantChecks: 3},
+		{name: "destination changed during the check", edit: "https://new.example.com", wantErr: ErrLinkChanged, wantStatus: HealthUnknown, wantChecks: 3},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.edit != "" {
+				var current URL
+				DB.First(&current, url.ID)
+				current.OriginalURL = step.edit
+				current.ResetHealth()
+				DB.Save(&current)
+			}
+
+			check := HealthCheck{Healthy: step.healthy, StatusCode: 500, CheckedAt: time.Now()}
+			previous, err := RecordHealthCheck(&probed, &check)
+			if !errors.Is(err, step.wantErr) {
+				t.Fatalf("RecordHealthCheck() error = %v, want %v", err, step.wantErr)
+			}
+			if err == nil && previous != step.wantPrevious {
+				t.Errorf("previous = %q, want %q", previous, step.wantPrevious)
+			}
+
+			var stored URL
+			DB.First(&stored, url.ID)
+			if stored.HealthStatus != step.wantStatus {
+				t.Errorf("health = %q, want %q", stored.HealthStatus, step.wantStatus)
+			}
+			var checks int64
+			DB.Model(&HealthCheck{}).Where("url_id = ?", url.ID).Count(&checks)
+			if checks != step.wantChecks {
+				t.Errorf("%d checks stored, want %d", checks, step.wantChecks)
+			}
+		})
+	}
+}
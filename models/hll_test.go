@@ -0,0 +1,115 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func visitorHash(i int) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(i))
+	sum := sha256.Sum256(b[:])
+	return sum[:]
+}
+
+func sketchOf(from, to int) HLL {
+	h := NewHLL()
+	for i := from; i < to; i++ {
+		h.Add(visitorHash(i))
+	}
+	return h
+}
+
+func TestHLLCount(t *testing.T) {
+	tests := []struct {
+		distinct  int
+		tolerance float64 // Relative error allowed
+	}{
+		{0, 0},
+		{1, 0},
+		{10, 0.1}, // Two of ten may share a register
+		{100, 0.05},
+		{1000, 0.06},
+		{10000, 0.09}, // Three standard errors
+		{100000, 0.09},
+	}
+	for _, tt := range tests {
+		got := sketchOf(0, tt.distinct).Count()
+		if diff := math.Abs(float64(got - int64(tt.distinct))); diff > tt.tolerance*float64(tt.distinct) {
+			t.Errorf("Count() of %d distinct = %d", tt.distinct, got)
+		}
+	}
+}
+
+func TestHLLDuplicates(t *testing.T) {
+	h := NewHLL()
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 500; i++ {
+			h.Add(visitorHash(i))
+		}
+	}
+	if got, want := h.Count(), sketchOf(0, 500).Count(); got != want {
+		t.Errorf("Count() with duplicates = %d, want %d", got, want)
+	}
+}
+
+func TestHLLMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   [2]int // Ranges of values added to each sketch
+		united [2]int
+	}{
+		{"disjoint", [2]int{0, 3000}, [2]int{3000, 5000}, [2]int{0, 5000}},
+		{"overlapping", [2]int{0, 3000}, [2]int{1000, 5000}, [2]int{0, 5000}},
+		{"contained", [2]int{0, 5000}, [2]int{1000, 2000}, [2]int{0, 5000}},
+		{"empty", [2]int{0, 0}, [2]int{0, 800}, [2]int{0, 800}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := sketchOf(tt.a[0], tt.a[1])
+			merged.Merge(sketchOf(tt.b[0], tt.b[1]))
+			if got, want := merged.Count(), sketchOf(tt.united[0], tt.united[1]).Count(); got != want {
+				t.Errorf("merged Count() = %d, want %d as if added to one sketch", got, want)
+			}
+		})
+	}
+}
+
+func TestParseHLL(t *testing.T) {
+	sketch := sketchOf(0, 300)
+	tests := []struct {
+		name string
+		data []byte
+		want int64
+	}{
+		{"stored sketch", []byte(sketch), sketch.Count()},
+		{"nil", nil, 0},
+		{"wrong size", []byte{1, 2, 3}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := ParseHLL(tt.data)
+			if len(parsed) != hllRegisters || parsed.Count() != tt.want {
+				t.Errorf("ParseHLL() has %d registers and counts %d, want %d", len(parsed), parsed.Count(), tt.want)
+			}
+		})
+	}
+
+	// The parsed sketch is a copy
+	parsed := ParseHLL(sketch)
+	parsed.Add(visitorHash(1 << 20))
+	if ParseHLL(sketch).Count() != sketch.Count() {
+		t.Error("adding to a parsed sketch changed the stored one")
+	}
+}
+
+func TestHLLAddShortHash(t *testing.T) {
+	h := NewHLL()
+	h.Add([]byte{1, 2, 3})
+	h.Add(nil)
+	if got := h.Count(); got != 0 {
+		t.Errorf("Count() after short hashes = %d, want 0", got)
+	}
+}
@@ -1,11 +1,61 @@
 package models
 
-import "time"
+import (
+	"regexp"
+	"time"
+)
 
+// Limits of a link-in-bio page
+const (
+	MaxPostsPerUser = 10
+	MaxPostBlocks   = 100
+)
+
+// Themes a page can be rendered with
+var PostThemes = []string{"light", "dark", "minimal"}
+
+var postSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// Post is a link-in-bio page: an ordered list of short links. The page with
+// an empty slug is served at /@username, the others at /@username/slug.
 type Post struct {
-	ID        int       `json:"id" gorm:"primary_key"`
-	Title     string    `json:"title"`
-	Body      string    `json:"body"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int         `json:"id" gorm:"primary_key"`
+	UserID    int         `json:"user_id" gorm:"uniqueIndex:idx_post_user_slug"`
+	Slug      string      `json:"slug" gorm:"not null;default:'';uniqueIndex:idx_post_user_slug"`
+	Title     string      `json:"title"`
+	Body      string      `json:"body"` // Description shown under the title
+	Theme     string      `json:"theme" gorm:"not null;default:'light'"`
+	Published bool        `json:"published" gorm:"not null;default:false"`
+	Blocks    []PostBlock `json:"blocks" gorm:"foreignKey:PostID"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// PostBlock is one short link on a page. Title and description default to
+// the link's own title and notes when empty.
+type PostBlock struct {
+	ID          int       `json:"id" gorm:"primary_key"`
+	PostID      int       `json:"post_id" gorm:"not null;index"`
+	Position    int       `json:"position" gorm:"not null"`
+	URLID       int       `json:"url_id" gorm:"not null;index"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ValidPostSlug reports whether slug can name a page: empty for the main
+// page, otherwise lower-case letters, digits and dashes.
+func ValidPostSlug(slug string) bool {
+	return slug == "" || postSlugPattern.MatchString(slug)
+}
+
+// ValidPostTheme reports whether theme is one of PostThemes.
+func ValidPostTheme(theme string) bool {
+	for _, t := range PostThemes {
+		if t == theme {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reasons for reporting a link
+var ReportReasons = []string{"phishing", "malware", "spam", "abuse", "other"}
+
+// Report statuses
+const (
+	ReportPending   = "pending"
+	ReportDismissed = "dismissed" // Reviewed, the link is fine
+	ReportActioned  = "actioned"  // Reviewed, the link was blocked
+)
+
+// AutoBlockThreshold is how many networks reporting a link on the same day
+// block it until an admin reviews it. Counting networks rather than people
+// keeps a single host or subnet from blocking links on its own.
+const AutoBlockThreshold = 5
+
+// AutoBlockCooldown is how long after an admin dismissed the reports of a
+// link new reports can't block it again on their own.
+const AutoBlockCooldown = 30 * 24 * time.Hour
+
+// Report is an abuse report about a link, sent by a visitor.
+type Report struct {
+	ID            int        `json:"id" gorm:"primary_key"`
+	URLID         int        `json:"url_id" gorm:"not null;index"`
+	Reason        string     `json:"reason" gorm:"not null"`
+	Details       string     `json:"details"`
+	ReporterEmail string     `json:"reporter_email"`
+	ReporterHash  string     `json:"-" gorm:"index"` // Visitor hash of the reporter, one pending report each
+	NetworkHash   string     `json:"-"`              // Visitor hash of the reporter's /24 or /48 network
+	Status        string     `json:"status" gorm:"not null;index;default:'pending'"`
+	ReviewedBy    *int       `json:"reviewed_by"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func ValidReportReason(reason string) bool {
+	for _, r := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateReport stores a report unless the reporter already has one pending
+// for the link. It returns whether the report was stored and from how many
+// networks the link was reported today. Network hashes change with the
+// daily salt, so only today's reports can be told apart.
+func CreateReport(report *Report) (created bool, networks int64, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		tx.Model(&Report{}).Where("url_id = ? AND reporter_hash = ? AND status = ?", report.URLID, report.ReporterHash, ReportPending).Count(&existing)
+		if existing == 0 {
+			report.Status = ReportPending
+			if err := tx.Create(report).Error; err != nil {
+				return err
+			}
+			created = true
+		}
+		return tx.Model(&Report{}).
+			Where("url_id = ? AND status = ? AND "+JulianDay("created_at")+" >= ?", report.URLID, ReportPending, ToJulianDay(DayBucket(time.Now()))).
+			Distinct("network_hash").Count(&networks).Error
+	})
+	return created, networks, err
+}
+
+// RecentlyDismissed reports whether an admin dismissed reports about the
+// link within AutoBlockCooldown.
+func RecentlyDismissed(urlID int) bool {
+	var count int64
+	DB.Model(&Report{}).
+		Where("url_id = ? AND status = ? AND "+JulianDay("reviewed_at")+" >= ?", urlID, ReportDismissed, ToJulianDay(time.Now().Add(-AutoBlockCooldown))).
+		Count(&count)
+	return count > 0
+}
+
+// ReviewReports closes the pending reports of a link.
+func ReviewReports(tx *gorm.DB, urlID int, status string, reviewerID int) error {
+	now := time.Now()
+	return tx.Model(&Report{}).Where("url_id = ? AND status = ?", urlID, ReportPending).Updates(map[string]interface{}{
+		"status":      status,
+		"reviewed_by": reviewerID,
+		"reviewed_at": now,
+	}).Error
+}
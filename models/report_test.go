@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCreateReportCountsTodaysNetworks(t *testing.T) {
+	setupTestDB(t)
+	link := URL{ShortCode: "reported", OriginalURL: "https://example.com"}
+	DB.Create(&link)
+
+	// Reported late yesterday by a server east of UTC: the stored text is
+	// today's date, but the network hash was made with yesterday's salt
+	wib := time.FixedZone("WIB", 7*60*60)
+	yesterday := DayBucket(time.Now()).Add(-time.Hour).In(wib)
+	DB.Create(&Report{URLID: link.ID, Reason: "spam", ReporterHash: "old", NetworkHash: "net-old", Status: ReportPending, CreatedAt: yesterday})
+
+	tests := []struct {
+		name         string
+		reporter     string
+		network      string
+		wantCreated  bool
+		wantNetworks int64
+	}{
+		{"first network", "r1", "net1", true, 1},
+		{"same reporter again", "r1", "net1", false, 1},
+		{"same network", "r2", "net1", true, 1},
+		{"second network", "r3", "net2", true, 2},
+		{"third network", "r4", "net3", true, 3},
+		{"fourth network", "r5", "net4", true, 4},
+		{"reaches the threshold", "r6", "net5", true, AutoBlockThreshold},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Report{URLID: link.ID, Reason: "spam", ReporterHash: tt.reporter, NetworkHash: tt.network}
+			created, networks, err := CreateReport(&report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created != tt.wantCreated || networks != tt.wantNetworks {
+				t.Errorf("CreateReport() = %v, %d, want %v, %d", created, networks, tt.wantCreated, tt.wantNetworks)
+			}
+		})
+	}
+}
+
+func TestRecentlyDismissed(t *testing.T) {
+	setupTestDB(t)
+	wib := time.FixedZone("WIB", 7*60*60)
+	tests := []struct {
+		reviewedAt time.Time
+		want       bool
+	}{
+		{time.Now().Add(-time.Hour).In(wib), true},
+		{time.Now().Add(-AutoBlockCooldown + time.Hour).UTC(), true},
+		{time.Now().Add(-AutoBlockCooldown - time.Hour).In(wib), false},
+	}
+	for i, tt := range tests {
+		link := URL{ShortCode: fmt.Sprint("dismissed", i), OriginalURL: "https://example.com"}
+		DB.Create(&link)
+		DB.Create(&Report{URLID: link.ID, Reason: "spam", Status: ReportDismissed, ReviewedAt: &tt.reviewedAt})
+		if got := RecentlyDismissed(link.ID); got != tt.want {
+			t.Errorf("RecentlyDismissed() reviewed at %v = %v, want %v", tt.reviewedAt, got, tt.want)
+		}
+	}
+}
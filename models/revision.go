@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// URLRevision is one version of a link's destination and short code. A new
+// revision is recorded whenever either changes, so the latest revision is the
+// current state and the earlier ones are its history.
+type URLRevision struct {
+	ID             int       `json:"id" gorm:"primary_key"`
+	URLID          int       `json:"url_id" gorm:"not null;uniqueIndex:idx_revision_url_version"`
+	Version        int       `json:"version" gorm:"not null;uniqueIndex:idx_revision_url_version"`
+	OriginalURL    string    `json:"original_url" gorm:"not null"`
+	ShortCode      string    `json:"short_code" gorm:"not null"`
+	EditedBy       int       `json:"edited_by"`
+	RolledBackFrom *int      `json:"rolled_back_from"` // Version restored by a rollback
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+}
+
+// RecordURLRevision stores the current destination and short code of a link
+// as its next version.
+func RecordURLRevision(db *gorm.DB, url URL, editedBy int, rolledBackFrom *int) (URLRevision, error) {
+	var latest int
+	if err := db.Model(&URLRevision{}).Where("url_id = ?", url.ID).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return URLRevision{}, err
+	}
+
+	revision := URLRevision{
+		URLID:          url.ID,
+		Version:        latest + 1,
+		OriginalURL:    url.OriginalURL,
+		ShortCode:      url.ShortCode,
+		EditedBy:       editedBy,
+		RolledBackFrom: rolledBackFrom,
+	}
+	err := db.Create(&revision).Error
+	return revision, err
+}
+
+// migrateURLRevisions records the first version of links created before
+// revisions existed.
+func migrateURLRevisions(db *gorm.DB) {
+	db.Exec(`INSERT INTO url_revisions (url_id, version, original_url, short_code, edited_by, created_at)
+		SELECT id, 1, original_url, short_code, user_id, created_at FROM urls
+		WHERE id NOT IN (SELECT url_id FROM url_revisions)`)
+}
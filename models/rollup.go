@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+	"modernc.org/sqlite"
+)
+
+// Rollups hold pre-aggregated click counts per URL, time bucket and
+// dimension, so analytics never has to scan the raw clicks table.
+// Buckets are always stored in UTC. Visitors is a HyperLogLog sketch of the
+// visitor hashes seen in the bucket.
+
+type HourlyClickRollup struct {
+	ID       int       `json:"id" gorm:"primary_key"`
+	URLID    int       `json:"url_id" gorm:"not null;uniqueIndex:idx_hourly_rollup_key"`
+	Bucket   time.Time `json:"bucket" gorm:"not null;index;uniqueIndex:idx_hourly_rollup_key"`
+	Country  string    `json:"country" gorm:"not null;default:'';uniqueIndex:idx_hourly_rollup_key"`
+	Referrer string    `json:"referrer" gorm:"not null;default:'';uniqueIndex:idx_hourly_rollup_key"`
+	Device   string    `json:"device" gorm:"not null;default:'';uniqueIndex:idx_hourly_rollup_key"`
+	Clicks   int64     `json:"clicks" gorm:"not null;default:0"`
+	Visitors []byte    `json:"-"`
+}
+
+type DailyClickRollup struct {
+	ID       int       `json:"id" gorm:"primary_key"`
+	URLID    int       `json:"url_id" gorm:"not null;uniqueIndex:idx_daily_rollup_key"`
+	Bucket   time.Time `json:"bucket" gorm:"not null;index;uniqueIndex:idx_daily_rollup_key"`
+	Country  string    `json:"country" gorm:"not null;default:'';uniqueIndex:idx_daily_rollup_key"`
+	Referrer string    `json:"referrer" gorm:"not null;default:'';uniqueIndex:idx_daily_rollup_key"`
+	Device   string    `json:"device" gorm:"not null;default:'';uniqueIndex:idx_daily_rollup_key"`
+	Clicks   int64     `json:"clicks" gorm:"not null;default:0"`
+	Visitors []byte    `json:"-"`
+}
+
+// HourBucket returns the start of the UTC hour containing t.
+func HourBucket(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// DayBucket returns the start of the UTC day containing t.
+func DayBucket(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type rollupKey struct {
+	URLID    int
+	Bucket   time.Time
+	Country  string
+	Referrer string
+	Device   string
+}
+
+type rollupDelta struct {
+	clicks   int64
+	visitors HLL
+}
+
+// rollupDeltas accumulates clicks per rollup key before they are written.
+type rollupDeltas map[rollupKey]*rollupDelta
+
+func (d rollupDeltas) add(key rollupKey, click *Click) {
+	delta, ok := d[key]
+	if !ok {
+		delta = &rollupDelta{visitors: NewHLL()}
+		d[key] = delta
+	}
+	delta.clicks++
+	if hash, err := hex.DecodeString(click.VisitorHash); err == nil {
+		delta.visitors.Add(hash)
+	}
+}
+
+func init() {
+	// hll_merge(a, b) merges two stored sketches, so rollup rows are
+	// updated in a single statement
+	sqlite.MustRegisterDeterministicScalarFunction("hll_merge", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		merged := ParseHLL(sketchBytes(args[0]))
+		merged.Merge(ParseHLL(sketchBytes(args[1])))
+		return []byte(merged), nil
+	})
+}
+
+func sketchBytes(v driver.Value) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+func incrementRollups(tx *gorm.DB, click *Click) error {
+	hourly := rollupDeltas{}
+	hourly.add(hourlyKey(click), click)
+	daily := rollupDeltas{}
+	daily.add(dailyKey(click), click)
+	return upsertRollups(tx, hourly, daily)
+}
+
+func hourlyKey(click *Click) rollupKey {
+	return rollupKey{click.URLID, HourBucket(click.ClickedAt), click.Country, click.Referrer, click.Device}
+}
+
+func dailyKey(click *Click) rollupKey {
+	return rollupKey{click.URLID, DayBucket(click.ClickedAt), click.Country, click.Referrer, click.Device}
+}
+
+// upsertRollups adds the given counts and visitors to the rollup rows,
+// creating rows that don't exist yet.
+func upsertRollups(tx *gorm.DB, hourly, daily rollupDeltas) error {
+	if err := upsertRollupTable(tx, "hourly_click_rollups", hourly); err != nil {
+		return err
+	}
+	return upsertRollupTable(tx, "daily_click_rollups", daily)
+}
+
+// upsertRollupTable writes each row with one INSERT ... ON CONFLICT, which
+// is atomic, so concurrent writers in this or another process (the backfill
+// command) neither lose counts nor collide on the unique key.
+func upsertRollupTable(tx *gorm.DB, table string, deltas rollupDeltas) error {
+	for key, delta := range deltas {
+		err := tx.Exec("INSERT INTO "+table+" (url_id, bucket, country, referrer, device, clicks, visitors) VALUES (?, ?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (url_id, bucket, country, referrer, device) DO UPDATE SET clicks = clicks + excluded.clicks, visitors = hll_merge(visitors, excluded.visitors)",
+			key.URLID, key.Bucket, key.Country, key.Referrer, key.Device, delta.clicks, []byte(delta.visitors)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildClickRollups throws away all rollup rows and recomputes them, and
+// the click counts of the links, from the raw clicks table. Clicks are read
+// in batches to keep memory bounded.
+func RebuildClickRollups(db *gorm.DB) (int64, error) {
+	var processed int64
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&HourlyClickRollup{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&DailyClickRollup{}).Error; err != nil {
+			return err
+		}
+
+		var batch []Click
+		err := tx.Model(&Click{}).FindInBatches(&batch, 1000, func(_ *gorm.DB, _ int) error {
+			hourly := rollupDeltas{}
+			daily := rollupDeltas{}
+			for i := range batch {
+				hourly.add(hourlyKey(&batch[i]), &batch[i])
+				daily.add(dailyKey(&batch[i]), &batch[i])
+			}
+			processed += int64(len(batch))
+			return upsertRollups(tx, hourly, daily)
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&URL{}).Unscoped().
+			UpdateColumn("click_count", gorm.Expr("(SELECT COUNT(*) FROM clicks WHERE clicks.url_id = urls.id)")).Error
+	})
+
+	return processed, err
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentRollupWrites(t *testing.T) {
+	setupTestDB(t)
+	link := URL{ShortCode: "busy", OriginalURL: "https://example.com"}
+	DB.Create(&link)
+
+	const clicks = 40
+	at := time.Date(2026, 5, 1, 10, 30, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	errs := make(chan error, clicks+1)
+	for i := 0; i < clicks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sum := sha256.Sum256([]byte(fmt.Sprint(i)))
+			errs <- RecordClick(&Click{URLID: link.ID, ClickedAt: at, Country: "ID", VisitorHash: hex.EncodeToString(sum[:])})
+		}()
+		if i == clicks/2 {
+			// The backfill command rebuilds while clicks come in
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := RebuildClickRollups(DB)
+				errs <- err
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, table := range []string{"hourly_click_rollups", "daily_click_rollups"} {
+		var rows []HourlyClickRollup
+		DB.Table(table).Find(&rows)
+		if len(rows) != 1 || rows[0].Clicks != clicks {
+			t.Fatalf("%s: %d rows, want one with %d clicks", table, len(rows), clicks)
+		}
+		if got := ParseHLL(rows[0].Visitors).Count(); got < clicks-2 || got > clicks+2 {
+			t.Errorf("%s: %d visitors, want about %d", table, got, clicks)
+		}
+	}
+	var stored URL
+	DB.First(&stored, link.ID)
+	if stored.ClickCount != clicks {
+		t.Errorf("click_count = %d, want %d", stored.ClickCount, clicks)
+	}
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MaxURLRules limits the routing rules of one link.
+const MaxURLRules = 20
+
+// Values accepted by the device and OS conditions of a rule
+var (
+	RuleDevices = []string{"mobile", "tablet", "desktop", "bot"}
+	RuleOSes    = []string{"ios", "android", "windows", "macos", "linux"}
+)
+
+// StringList is a list of strings stored as JSON text.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case []byte:
+		return json.Unmarshal(v, l)
+	}
+	return fmt.Errorf("unsupported string list type %T", value)
+}
+
+func (StringList) GormDataType() string {
+	return "text"
+}
+
+// Contains reports whether the list holds value.
+func (l StringList) Contains(value string) bool {
+	for _, v := range l {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Visit is what is known about the visitor of a short link when it redirects.
+type Visit struct {
+	Country  string // ISO code from the CDN, empty when unknown
+	Device   string
+	OS       string
+	Language string // Preferred language from Accept-Language, lower-cased
+	Referrer string // Referrer host without "www."
+	Query    url.Values
+}
+
+// URLRule routes the visitors of a link that match all of its conditions to
+// another destination. Empty conditions match everyone; within a condition
+// any of the values may match. Rules are evaluated by position and the first
+// match wins.
+type URLRule struct {
+	ID             int        `json:"id" gorm:"primary_key"`
+	URLID          int        `json:"url_id" gorm:"not null;index"`
+	Position       int        `json:"position" gorm:"not null"`
+	Name           string     `json:"name"`
+	DestinationURL string     `json:"destination_url" gorm:"not null"`
+	Countries      StringList `json:"countries"`
+	Devices        StringList `json:"devices"`
+	OSes           StringList `json:"os" gorm:"column:oses"`
+	Languages      StringList `json:"languages"`    // "en" matches "en-US" too
+	Referrers      StringList `json:"referrers"`    // Subdomains match too
+	QueryParams    StringList `json:"query_params"` // "key=value", or "key" for any value
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// HasConditions reports whether the rule narrows down its visitors at all.
+func (r URLRule) HasConditions() bool {
+	return len(r.Countries)+len(r.Devices)+len(r.OSes)+len(r.Languages)+len(r.Referrers)+len(r.QueryParams) > 0
+}
+
+// Matches reports whether the visit meets all conditions of the rule.
+func (r URLRule) Matches(visit Visit) bool {
+	if len(r.Countries) > 0 && !r.Countries.Contains(visit.Country) {
+		return false
+	}
+	if len(r.Devices) > 0 && !r.Devices.Contains(visit.Device) {
+		return false
+	}
+	if len(r.OSes) > 0 && !r.OSes.Contains(visit.OS) {
+		return false
+	}
+	if len(r.Languages) > 0 && !matchesAny(r.Languages, func(lang string) bool {
+		return visit.Language == lang || strings.HasPrefix(visit.Language, lang+"-")
+	}) {
+		return false
+	}
+	if len(r.Referrers) > 0 && !matchesAny(r.Referrers, func(host string) bool {
+		return visit.Referrer == host || strings.HasSuffix(visit.Referrer, "."+host)
+	}) {
+		return false
+	}
+	if len(r.QueryParams) > 0 && !matchesAny(r.QueryParams, func(param string) bool {
+		key, value, hasValue := strings.Cut(param, "=")
+		if !visit.Query.Has(key) {
+			return false
+		}
+		return !hasValue || visit.Query.Get(key) == value
+	}) {
+		return false
+	}
+	return true
+}
+
+func matchesAny(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchRule returns the first rule of the link matching the visit, or nil.
+// The rules must be loaded in position order.
+func (u URL) MatchRule(visit Visit) *URLRule {
+	for i := range u.Rules {
+		if u.Rules[i].Matches(visit) {
+			return &u.Rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestURLRuleMatches(t *testing.T) {
+	visit := Visit{
+		Country:  "ID",
+		Device:   "mobile",
+		OS:       "android",
+		Language: "en-us",
+		Referrer: "m.facebook.com",
+		Query:    url.Values{"ref": {"newsletter"}, "debug": {""}},
+	}
+	tests := []struct {
+		name string
+		rule URLRule
+		want bool
+	}{
+		{"no conditions", URLRule{}, true},
+		{"country", URLRule{Countries: StringList{"SG", "ID"}}, true},
+		{"other country", URLRule{Countries: StringList{"SG"}}, false},
+		{"device", URLRule{Devices: StringList{"mobile"}}, true},
+		{"other device", URLRule{Devices: StringList{"desktop", "tablet"}}, false},
+		{"os", URLRule{OSes: StringList{"ios", "android"}}, true},
+		{"other os", URLRule{OSes: StringList{"ios"}}, false},
+		{"language prefix", URLRule{Languages: StringList{"en"}}, true},
+		{"exact language", URLRule{Languages: StringList{"en-us"}}, true},
+		{"other region", URLRule{Languages: StringList{"en-gb"}}, false},
+		{"prefix without a dash", URLRule{Languages: StringList{"e"}}, false},
+		{"referrer subdomain", URLRule{Referrers: StringList{"facebook.com"}}, true},
+		{"exact referrer", URLRule{Referrers: StringList{"m.facebook.com"}}, true},
+		{"referrer suffix without a dot", URLRule{Referrers: StringList{"book.com"}}, false},
+		{"query key", URLRule{QueryParams: StringList{"debug"}}, true},
+		{"query key and value", URLRule{QueryParams: StringList{"ref=newsletter"}}, true},
+		{"query key with other value", URLRule{QueryParams: StringList{"ref=ads"}}, false},
+		{"missing query key", URLRule{QueryParams: StringList{"utm"}}, false},
+		{"empty query value", URLRule{QueryParams: StringList{"debug="}}, true},
+		{"all conditions", URLRule{Countries: StringList{"ID"}, Devices: StringList{"mobile"}, Languages: StringList{"en"}}, true},
+		{"one condition fails", URLRule{Countries: StringList{"ID"}, Devices: StringList{"desktop"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(visit); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRule(t *testing.T) {
+	link := URL{Rules: []URLRule{
+		{ID: 1, Countries: StringList{"US"}},
+		{ID: 2, Devices: StringList{"mobile"}},
+		{ID: 3, Languages: StringList{"id"}},
+	}}
+	tests := []struct {
+		name  string
+		visit Visit
+		want  int // Rule ID, 0 for none
+	}{
+		{"first match wins", Visit{Country: "US", Device: "mobile"}, 1},
+		{"later rule", Visit{Country: "ID", Device: "mobile"}, 2},
+		{"last rule", Visit{Device: "desktop", Language: "id"}, 3},
+		{"no match", Visit{Country: "ID", Device: "desktop"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := 0
+			if rule := link.MatchRule(tt.visit); rule != nil {
+				got = rule.ID
+			}
+			if got != tt.want {
+				t.Errorf("MatchRule() = rule %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// MaxURLSchedules limits the destination rules of one link.
+const MaxURLSchedules = 20
+
+// URLSchedule sends a link to another destination during a time window.
+// Either bound may be open. When windows overlap the rule with the lowest
+// position wins.
+type URLSchedule struct {
+	ID             int        `json:"id" gorm:"primary_key"`
+	URLID          int        `json:"url_id" gorm:"not null;index"`
+	Position       int        `json:"position" gorm:"not null"`
+	DestinationURL string     `json:"destination_url" gorm:"not null"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"` // Exclusive
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Covers reports whether now falls within the rule's window.
+func (s URLSchedule) Covers(now time.Time) bool {
+	return (s.StartsAt == nil || !now.Before(*s.StartsAt)) && (s.EndsAt == nil || now.Before(*s.EndsAt))
+}
+
+// IsActive reports whether the link has gone live.
+func (u URL) IsActive(now time.Time) bool {
+	return u.ActiveFrom == nil || !now.Before(*u.ActiveFrom)
+}
+
+// IsDynamic reports whether the destination of the link depends on the time,
+// the visitor or the visit, so redirects to it must not be cached.
+func (u URL) IsDynamic() bool {
+	return u.ActiveFrom != nil || len(u.Schedules) > 0 || len(u.Rules) > 0 || len(u.Variants) > 0 ||
+		u.TrackConversions || u.IOS.IsSet() || u.Android.IsSet()
+}
+
+// ScheduleAt returns the first schedule covering the given time, or nil. The
+// schedules must be loaded in position order.
+func (u URL) ScheduleAt(now time.Time) *URLSchedule {
+	for i := range u.Schedules {
+		if u.Schedules[i].Covers(now) {
+			return &u.Schedules[i]
+		}
+	}
+	return nil
+}
+
+// Destination returns where the link points at the given time: the first
+// schedule covering it, otherwise the original URL.
+func (u URL) Destination(now time.Time) string {
+	if schedule := u.ScheduleAt(now); schedule != nil {
+		return schedule.DestinationURL
+	}
+	return u.OriginalURL
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleAt(t *testing.T) {
+	at := func(hour int) *time.Time {
+		t := time.Date(2026, 6, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	link := URL{OriginalURL: "https://original.example.com", Schedules: []URLSchedule{
+		{ID: 1, StartsAt: at(10), EndsAt: at(12), DestinationURL: "https://sale.example.com"},
+		{ID: 2, StartsAt: at(11), EndsAt: at(14), DestinationURL: "https://later.example.com"},
+		{ID: 3, StartsAt: at(20), DestinationURL: "https://open-end.example.com"},
+		{ID: 4, EndsAt: at(2), DestinationURL: "https://open-start.example.com"},
+	}}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		want    int // Schedule ID, 0 for none
+		wantURL string
+	}{
+		{"before an open start ends", *at(1), 4, "https://open-start.example.com"},
+		{"end is exclusive", *at(2), 0, "https://original.example.com"},
+		{"start is inclusive", *at(10), 1, "https://sale.example.com"},
+		{"overlap picks the lower position", *at(11), 1, "https://sale.example.com"},
+		{"after the first window ends", *at(12), 2, "https://later.example.com"},
+		{"between windows", *at(15), 0, "https://original.example.com"},
+		{"open end", at(20).AddDate(1, 0, 0), 3, "https://open-end.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := 0
+			if schedule := link.ScheduleAt(tt.now); schedule != nil {
+				got = schedule.ID
+			}
+			if got != tt.want {
+				t.Errorf("ScheduleAt() = schedule %d, want %d", got, tt.want)
+			}
+			if destination := link.Destination(tt.now); destination != tt.wantURL {
+				t.Errorf("Destination() = %q, want %q", destination, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestIsActive(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	tests := []struct {
+		name       string
+		activeFrom *time.Time
+		want       bool
+	}{
+		{"no activation time", nil, true},
+		{"already live", &past, true},
+		{"goes live now", &now, true},
+		{"not live yet", &future, false},
+	}
+	for _, tt := range tests {
+		if got := (URL{ActiveFrom: tt.activeFrom}).IsActive(now); got != tt.want {
+			t.Errorf("%s: IsActive() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// FTSEnabled is true when the SQLite build supports FTS5 and the urls_fts
+// index is in place. Search falls back to LIKE otherwise.
+var FTSEnabled bool
+
+// setupURLSearch creates the FTS5 index over the searchable URL columns and
+// the triggers that keep it in sync with the urls table.
+func setupURLSearch(db *gorm.DB) {
+	var exists bool
+	db.Raw("SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'urls_fts'").Scan(&exists)
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS urls_fts USING fts5(original_url, short_code, title, notes, content='urls', content_rowid='id')`,
+		`CREATE TRIGGER IF NOT EXISTS urls_fts_insert AFTER INSERT ON urls BEGIN
+			INSERT INTO urls_fts(rowid, original_url, short_code, title, notes) VALUES (new.id, new.original_url, new.short_code, new.title, new.notes);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS urls_fts_delete AFTER DELETE ON urls BEGIN
+			INSERT INTO urls_fts(urls_fts, rowid, original_url, short_code, title, notes) VALUES ('delete', old.id, old.original_url, old.short_code, old.title, old.notes);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS urls_fts_update AFTER UPDATE OF original_url, short_code, title, notes ON urls BEGIN
+			INSERT INTO urls_fts(urls_fts, rowid, original_url, short_code, title, notes) VALUES ('delete', old.id, old.original_url, old.short_code, old.title, old.notes);
+			INSERT INTO urls_fts(rowid, original_url, short_code, title, notes) VALUES (new.id, new.original_url, new.short_code, new.title, new.notes);
+		END`,
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			log.Printf("Full-text search disabled: %v", err)
+			return
+		}
+	}
+
+	// Index the links that existed before the index did
+	if !exists {
+		db.Exec("INSERT INTO urls_fts(urls_fts) VALUES ('rebuild')")
+	}
+
+	FTSEnabled = true
+}
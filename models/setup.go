@@ -11,7 +11,10 @@ import (
 var DB *gorm.DB
 
 func ConnectDB() {
-	sqlDB, err := sql.Open("sqlite", "db.sqlite")
+	// Writers wait for each other instead of failing with "database is
+	// locked", and transactions take the write lock when they begin, so one
+	// that reads before writing can't deadlock with another process
+	sqlDB, err := sql.Open("sqlite", "db.sqlite?_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		panic("failed to connect database: " + err.Error())
 	}
@@ -21,11 +24,35 @@ func ConnectDB() {
 		panic("failed to connect database: " + err.Error())
 	}
 
-	database.AutoMigrate(&Post{}, &URL{}, &User{}, &Click{})
+	// Columns added to existing tables that need their values backfilled
+	backfillDomains := !columnExists(database, "urls", "domain")
+	backfillLastClicked := !columnExists(database, "urls", "last_clicked_at")
+
+	// Tag names used to be unique per user, they are now unique per workspace
+	database.Exec("DROP INDEX IF EXISTS idx_tag_user_name")
+
+	database.AutoMigrate(&Post{}, &URL{}, &User{}, &Click{}, &HourlyClickRollup{}, &DailyClickRollup{}, &VisitorSalt{}, &Webhook{}, &WebhookDelivery{}, &Tag{}, &Folder{}, &Workspace{}, &Membership{}, &Invitation{}, &LinkTransfer{}, &LinkTransferItem{}, &AuditEvent{}, &URLRevision{}, &URLSchedule{}, &URLRule{}, &URLVariant{}, &Conversion{}, &PostBlock{}, &ErrorPage{}, &HealthCheck{}, &Report{})
 
 	// Manually add user_id column if it doesn't exist
 	migrateUserIDColumn(database)
 
+	// Links and the rest used to belong to a user, move them to the user's
+	// personal workspace
+	migratePersonalWorkspaces(database)
+
+	// Links created before revisions existed start their history at version 1
+	migrateURLRevisions(database)
+
+	// Fill the domain and last click time of links created before those columns existed
+	if backfillDomains {
+		migrateURLDomains(database)
+	}
+	if backfillLastClicked {
+		migrateLastClickedAt(database)
+	}
+
+	setupURLSearch(database)
+
 	DB = database
 }
 
@@ -39,3 +66,22 @@ func migrateUserIDColumn(db *gorm.DB) {
 		db.Exec("ALTER TABLE urls ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0")
 	}
 }
+
+func migrateURLDomains(db *gorm.DB) {
+	var urls []URL
+	db.Select("id", "original_url").Find(&urls)
+
+	for _, url := range urls {
+		db.Model(&URL{}).Where("id = ?", url.ID).UpdateColumn("domain", DomainOf(url.OriginalURL))
+	}
+}
+
+func migrateLastClickedAt(db *gorm.DB) {
+	db.Exec("UPDATE urls SET last_clicked_at = (SELECT MAX(clicked_at) FROM clicks WHERE clicks.url_id = urls.id)")
+}
+
+func columnExists(db *gorm.DB, table, column string) bool {
+	var exists bool
+	db.Raw("SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&exists)
+	return exists
+}
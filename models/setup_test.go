@@ -0,0 +1,15 @@
+package models
+
+import "testing"
+
+// setupTestDB connects to a fresh database in a temporary directory.
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	ConnectDB()
+	t.Cleanup(func() {
+		if sqlDB, err := DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Times are stored by the SQLite driver as the text of time.Time.String(),
+// like "2026-05-01 19:00:00.5 +0700 WIB", with the offset of the process
+// that wrote them. That text doesn't sort by time once offsets differ, so
+// queries comparing stored times compare Julian days instead.
+
+// JulianDay returns the SQL converting a stored time to a Julian day, to the
+// millisecond. It rebuilds "2026-05-01 19:00:00.5+07:00", which SQLite
+// understands, and gives NULL for NULL or empty times.
+func JulianDay(expr string) string {
+	rest := "substr(" + expr + ", 20)" // Fraction, offset and zone name
+	space := "instr(" + rest + ", ' ')"
+	return "julianday(substr(" + expr + ", 1, 19) || substr(" + rest + ", 1, " + space + " - 1) || " +
+		"substr(" + rest + ", " + space + " + 1, 3) || ':' || substr(" + rest + ", " + space + " + 4, 2))"
+}
+
+// ToJulianDay returns the Julian day of t, to compare with JulianDay.
+func ToJulianDay(t time.Time) float64 {
+	return float64(t.UnixMilli())/86400000 + 2440587.5
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Tag struct {
+	ID          int       `json:"id" gorm:"primary_key"`
+	UserID      int       `json:"user_id" gorm:"not null"`
+	WorkspaceID int       `json:"workspace_id" gorm:"uniqueIndex:idx_tag_workspace_name"`
+	Name        string    `json:"name" gorm:"not null;uniqueIndex:idx_tag_workspace_name"`
+	Color       string    `json:"color"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FindOrCreateTags returns the workspace's tags with the given names, creating
+// the ones that don't exist yet on behalf of the user. Pass the transaction
+// saving the link, so new tags are rolled back with it.
+func FindOrCreateTags(db *gorm.DB, workspaceID, userID int, names []string) ([]Tag, error) {
+	tags := make([]Tag, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		tag := Tag{UserID: userID, WorkspaceID: workspaceID, Name: name}
+		if err := db.Where(Tag{WorkspaceID: workspaceID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// FindTags returns the workspace's tags with the given names, leaving out the
+// ones that don't exist.
+func FindTags(db *gorm.DB, workspaceID int, names []string) ([]Tag, error) {
+	var tags []Tag
+	err := db.Where("workspace_id = ? AND name IN ?", workspaceID, names).Find(&tags).Error
+	return tags, err
+}
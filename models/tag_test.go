@@ -0,0 +1,39 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestFindOrCreateTagsRollsBack(t *testing.T) {
+	setupTestDB(t)
+	DB.Create(&Tag{WorkspaceID: 1, Name: "existing"})
+
+	errFailed := errors.New("saving the link failed")
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := FindOrCreateTags(tx, 1, 1, []string{"existing", "new", "new", ""})
+		if err != nil {
+			return err
+		}
+		if len(tags) != 2 {
+			t.Errorf("FindOrCreateTags() = %d tags, want 2", len(tags))
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatal(err)
+	}
+
+	var names []string
+	DB.Model(&Tag{}).Order("name").Pluck("name", &names)
+	if len(names) != 1 || names[0] != "existing" {
+		t.Errorf("tags after the rollback = %v, want [existing]", names)
+	}
+
+	found, err := FindTags(DB, 1, []string{"existing", "missing"})
+	if err != nil || len(found) != 1 || found[0].Name != "existing" {
+		t.Errorf("FindTags() = %v, %v", found, err)
+	}
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// MaxPathParams limits the named path segments of one link.
+const MaxPathParams = 10
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	paramNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	ErrPlaceholderInHost = errors.New("placeholders are only allowed in the path and query of the destination")
+	ErrInvalidLinkPath   = errors.New("path segments can't contain an escaped slash")
+)
+
+// LinkPath is the part of a request path after the short code of a prefix
+// link, and the query string of the request.
+type LinkPath struct {
+	Segments []string
+	Query    neturl.Values
+}
+
+// FindLinkByPath returns the link for an escaped request path: the link
+// whose short code is the whole path, otherwise the prefix link with the
+// longest short code the path starts with, segment by segment. The segments
+// after the code are returned unescaped and without "." and "..", so they
+// can't climb out of the destination's path. Paths with an escaped slash in
+// a segment are rejected with ErrInvalidLinkPath.
+func FindLinkByPath(escapedPath string) (URL, []string, error) {
+	segments, err := unescapeSegments(strings.Trim(escapedPath, "/"))
+	if err != nil {
+		return URL{}, nil, err
+	}
+	candidates := make([]string, len(segments))
+	for i := range segments {
+		candidates[i] = strings.Join(segments[:i+1], "/")
+	}
+	full := candidates[len(candidates)-1]
+
+	var url URL
+	err = DB.Where("short_code IN ?", candidates).
+		Where("short_code = ? OR match_prefix = ?", full, true).
+		Order("LENGTH(short_code) DESC").
+		First(&url).Error
+	if err != nil {
+		return url, nil, err
+	}
+	return url, cleanSegments(segments[strings.Count(url.ShortCode, "/")+1:]), nil
+}
+
+// LinkPathSegments splits the escaped path after the short code of a prefix
+// link the way FindLinkByPath does.
+func LinkPathSegments(escapedRest string) ([]string, error) {
+	segments, err := unescapeSegments(strings.Trim(escapedRest, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return cleanSegments(segments), nil
+}
+
+func unescapeSegments(escapedPath string) ([]string, error) {
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		unescaped, err := neturl.PathUnescape(segment)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(unescaped, "/") {
+			return nil, ErrInvalidLinkPath
+		}
+		segments[i] = unescaped
+	}
+	return segments, nil
+}
+
+func cleanSegments(segments []string) []string {
+	var clean []string
+	for _, segment := range segments {
+		if segment != "" && segment != "." && segment != ".." {
+			clean = append(clean, segment)
+		}
+	}
+	return clean
+}
+
+// ValidatePathParams checks the names of a link's path segments.
+func ValidatePathParams(names []string) error {
+	if len(names) > MaxPathParams {
+		return fmt.Errorf("a link can have at most %d path params", MaxPathParams)
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !paramNamePattern.MatchString(name) || name == "path" || name == "query" {
+			return fmt.Errorf("invalid path param name %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate path param %q", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// ValidateTemplate checks that a destination has no placeholders before its
+// path, so visitors can't choose the host they are sent to.
+func ValidateTemplate(destination string) error {
+	if placeholderPattern.MatchString(destination[:authorityEnd(destination)]) {
+		return ErrPlaceholderInHost
+	}
+	return nil
+}
+
+// ExpandDestination fills in the placeholders of a destination: {path} is
+// the path after the short code, {query} the request's query string and
+// {name} the path segment named by the link's path params. Values are
+// escaped for the part of the URL they land in, and placeholders before the
+// path are left alone. A prefix link whose destination has neither {path}
+// nor named segments gets the rest of the path appended to the
+// destination's path, each segment escaped on its own.
+func (u URL) ExpandDestination(destination string, path LinkPath) string {
+	values := map[string]func(inQuery bool) string{
+		"path": func(inQuery bool) string {
+			if inQuery {
+				return neturl.QueryEscape(strings.Join(path.Segments, "/"))
+			}
+			escaped := make([]string, len(path.Segments))
+			for i, segment := range path.Segments {
+				escaped[i] = neturl.PathEscape(segment)
+			}
+			return strings.Join(escaped, "/")
+		},
+		"query": func(bool) string {
+			return path.Query.Encode()
+		},
+	}
+	for i, name := range u.PathParams {
+		segment := ""
+		if i < len(path.Segments) {
+			segment = path.Segments[i]
+		}
+		values[name] = func(inQuery bool) string {
+			if inQuery {
+				return neturl.QueryEscape(segment)
+			}
+			return neturl.PathEscape(segment)
+		}
+	}
+
+	start := authorityEnd(destination)
+	queryStart := strings.IndexAny(destination, "?#")
+	hasPath := false
+
+	var expanded strings.Builder
+	last := 0
+	for _, match := range placeholderPattern.FindAllStringSubmatchIndex(destination, -1) {
+		name := destination[match[2]:match[3]]
+		value, ok := values[name]
+		if !ok || match[0] < start {
+			continue
+		}
+		if name != "query" {
+			hasPath = true
+		}
+		expanded.WriteString(destination[last:match[0]])
+		expanded.WriteString(value(queryStart >= 0 && match[0] > queryStart))
+		last = match[1]
+	}
+	expanded.WriteString(destination[last:])
+
+	if !u.MatchPrefix || hasPath || len(path.Segments) == 0 {
+		return expanded.String()
+	}
+	parsed, err := neturl.Parse(expanded.String())
+	if err != nil {
+		return expanded.String()
+	}
+	// Not JoinPath: it cleans "..", and takes its elements as already escaped
+	escaped := make([]string, 0, len(path.Segments)+1)
+	escaped = append(escaped, strings.TrimSuffix(parsed.EscapedPath(), "/"))
+	for _, segment := range path.Segments {
+		escaped = append(escaped, neturl.PathEscape(segment))
+	}
+	rawPath := strings.Join(escaped, "/")
+	if parsed.Path, err = neturl.PathUnescape(rawPath); err != nil {
+		return expanded.String()
+	}
+	parsed.RawPath = rawPath
+	return parsed.String()
+}
+
+// authorityEnd returns where the path of a URL starts, after its scheme and
+// host.
+func authorityEnd(rawURL string) int {
+	start := 0
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if i := strings.IndexAny(rawURL[start:], "/?#"); i >= 0 {
+		return start + i
+	}
+	return len(rawURL)
+}
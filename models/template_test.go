@@ -0,0 +1,182 @@
+package models
+
+import (
+	"errors"
+	neturl "net/url"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestExpandDestination(t *testing.T) {
+	tests := []struct {
+		name        string
+		link        URL
+		destination string
+		path        LinkPath
+		want        string
+	}{
+		{
+			name:        "prefix appends the rest of the path",
+			link:        URL{MatchPrefix: true},
+			destination: "https://docs.example.com/guide",
+			path:        LinkPath{Segments: []string{"intro", "setup"}},
+			want:        "https://docs.example.com/guide/intro/setup",
+		},
+		{
+			name:        "prefix keeps the destination's query",
+			link:        URL{MatchPrefix: true},
+			destination: "https://docs.example.com/guide/?lang=en",
+			path:        LinkPath{Segments: []string{"intro"}},
+			want:        "https://docs.example.com/guide/intro?lang=en",
+		},
+		{
+			name:        "prefix escapes slashes inside a segment",
+			link:        URL{MatchPrefix: true},
+			destination: "https://docs.example.com/guide",
+			path:        LinkPath{Segments: []string{"../../admin"}},
+			want:        "https://docs.example.com/guide/..%2F..%2Fadmin",
+		},
+		{
+			name:        "prefix keeps a percent sign",
+			link:        URL{MatchPrefix: true},
+			destination: "https://docs.example.com/guide",
+			path:        LinkPath{Segments: []string{"100%", "a b"}},
+			want:        "https://docs.example.com/guide/100%25/a%20b",
+		},
+		{
+			name:        "prefix keeps escapes of the destination",
+			link:        URL{MatchPrefix: true},
+			destination: "https://docs.example.com/a%2Fb",
+			path:        LinkPath{Segments: []string{"c"}},
+			want:        "https://docs.example.com/a%2Fb/c",
+		},
+		{
+			name:        "prefix without a path",
+			link:        URL{MatchPrefix: true},
+			destination: "https://docs.example.com",
+			path:        LinkPath{Segments: []string{"intro"}},
+			want:        "https://docs.example.com/intro",
+		},
+		{
+			name:        "path placeholder",
+			link:        URL{MatchPrefix: true},
+			destination: "https://docs.example.com/v2/{path}?ref=short",
+			path:        LinkPath{Segments: []string{"a b", "c/d"}},
+			want:        "https://docs.example.com/v2/a%20b/c%2Fd?ref=short",
+		},
+		{
+			name:        "path in the query",
+			link:        URL{MatchPrefix: true},
+			destination: "https://example.com/search?q={path}",
+			path:        LinkPath{Segments: []string{"a&b", "c"}},
+			want:        "https://example.com/search?q=a%26b%2Fc",
+		},
+		{
+			name:        "query placeholder",
+			destination: "https://example.com/landing?{query}",
+			path:        LinkPath{Query: neturl.Values{"utm_source": {"x y"}}},
+			want:        "https://example.com/landing?utm_source=x+y",
+		},
+		{
+			name:        "named params",
+			link:        URL{MatchPrefix: true, PathParams: StringList{"user", "repo"}},
+			destination: "https://github.com/{user}/{repo}",
+			path:        LinkPath{Segments: []string{"go%lang", ".."}},
+			want:        "https://github.com/go%25lang/..",
+		},
+		{
+			name:        "missing named param is empty",
+			link:        URL{MatchPrefix: true, PathParams: StringList{"id"}},
+			destination: "https://example.com/items/{id}",
+			want:        "https://example.com/items/",
+		},
+		{
+			name:        "placeholders before the path are left alone",
+			link:        URL{MatchPrefix: true, PathParams: StringList{"host"}},
+			destination: "https://{host}.example.com/{path}",
+			path:        LinkPath{Segments: []string{"evil"}},
+			want:        "https://{host}.example.com/evil",
+		},
+		{
+			name:        "not a prefix link",
+			destination: "https://example.com/page",
+			path:        LinkPath{Segments: []string{"ignored"}},
+			want:        "https://example.com/page",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.link.ExpandDestination(tt.destination, tt.path); got != tt.want {
+				t.Errorf("ExpandDestination() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindLinkByPath(t *testing.T) {
+	setupTestDB(t)
+	links := []URL{
+		{ShortCode: "docs", OriginalURL: "https://docs.example.com/guide", MatchPrefix: true},
+		{ShortCode: "docs/api", OriginalURL: "https://api.example.com", MatchPrefix: true},
+		{ShortCode: "exact", OriginalURL: "https://example.com"},
+	}
+	for i := range links {
+		if err := DB.Create(&links[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		wantCode     string
+		wantSegments []string
+		wantErr      error
+		wantTarget   string
+	}{
+		{name: "exact code", path: "/exact", wantCode: "exact"},
+		{name: "exact code only", path: "/exact/more", wantErr: gorm.ErrRecordNotFound},
+		{name: "prefix", path: "/docs/intro/setup", wantCode: "docs", wantSegments: []string{"intro", "setup"},
+			wantTarget: "https://docs.example.com/guide/intro/setup"},
+		{name: "longest prefix", path: "/docs/api/users", wantCode: "docs/api", wantSegments: []string{"users"},
+			wantTarget: "https://api.example.com/users"},
+		{name: "dot segments are dropped", path: "/docs/../../admin", wantCode: "docs", wantSegments: []string{"admin"},
+			wantTarget: "https://docs.example.com/guide/admin"},
+		{name: "escaped dot segments are dropped", path: "/docs/%2e%2e/%2E%2E/admin", wantCode: "docs", wantSegments: []string{"admin"},
+			wantTarget: "https://docs.example.com/guide/admin"},
+		{name: "escaped slashes are rejected", path: "/docs/..%2F..%2Fadmin", wantErr: ErrInvalidLinkPath},
+		{name: "escaped slash in the code is rejected", path: "/docs%2Fapi/users", wantErr: ErrInvalidLinkPath},
+		{name: "escaped percent sign", path: "/docs/100%25", wantCode: "docs", wantSegments: []string{"100%"},
+			wantTarget: "https://docs.example.com/guide/100%25"},
+		{name: "invalid escape is kept", path: "/docs/%zz", wantCode: "docs", wantSegments: []string{"%zz"},
+			wantTarget: "https://docs.example.com/guide/%25zz"},
+		{name: "unknown", path: "/nope", wantErr: gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, segments, err := FindLinkByPath(tt.path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FindLinkByPath() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindLinkByPath() error = %v", err)
+			}
+			if link.ShortCode != tt.wantCode {
+				t.Errorf("short code = %q, want %q", link.ShortCode, tt.wantCode)
+			}
+			if !reflect.DeepEqual(segments, tt.wantSegments) {
+				t.Errorf("segments = %q, want %q", segments, tt.wantSegments)
+			}
+			if tt.wantTarget != "" {
+				if got := link.ExpandDestination(link.OriginalURL, LinkPath{Segments: segments}); got != tt.wantTarget {
+					t.Errorf("destination = %q, want %q", got, tt.wantTarget)
+				}
+			}
+		})
+	}
+}
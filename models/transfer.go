@@ -0,0 +1,143 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Transfer statuses
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferDeclined  = "declined"
+	TransferCancelled = "cancelled"
+)
+
+var ErrTransferLinksChanged = errors.New("links changed workspace since the transfer was requested")
+
+// ErrTransferNotAllowed is returned when links would leave their workspace
+// but the requester is no longer one of its admins.
+var ErrTransferNotAllowed = errors.New("only workspace admins can move links out of the workspace")
+
+// LinkTransfer moves links to another user or workspace once the receiving
+// side accepts. Together with its items it is the audit trail of the move.
+type LinkTransfer struct {
+	ID              int                `json:"id" gorm:"primary_key"`
+	FromWorkspaceID int                `json:"from_workspace_id" gorm:"not null;index"`
+	ToWorkspaceID   *int               `json:"to_workspace_id" gorm:"index"` // Set for transfers to a workspace
+	ToUserID        *int               `json:"to_user_id" gorm:"index"`      // Set for transfers to a user
+	Note            string             `json:"note"`
+	Status          string             `json:"status" gorm:"not null;index;default:'pending'"`
+	RequestedBy     int                `json:"requested_by" gorm:"not null"`
+	RespondedBy     *int               `json:"responded_by"`
+	RespondedAt     *time.Time         `json:"responded_at"`
+	Items           []LinkTransferItem `json:"items" gorm:"foreignKey:TransferID"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// LinkTransferItem is one link of a transfer, with where it came from and
+// where it ended up.
+type LinkTransferItem struct {
+	ID                  int  `json:"id" gorm:"primary_key"`
+	TransferID          int  `json:"transfer_id" gorm:"not null;index"`
+	URLID               int  `json:"url_id" gorm:"not null;index"`
+	PreviousUserID      int  `json:"previous_user_id"`
+	PreviousWorkspaceID int  `json:"previous_workspace_id"`
+	NewUserID           *int `json:"new_user_id"`
+	NewWorkspaceID      *int `json:"new_workspace_id"`
+}
+
+// AcceptLinkTransfer moves the links of a pending transfer. A transfer to a
+// user makes them the owner of the links, keeping the workspace when they are
+// a member of it and moving the links to their personal workspace otherwise.
+// A transfer to a workspace only changes the workspace. Links that change
+// workspace lose their folder and get their tags recreated in the new one;
+// clicks and rollups reference the link and stay with it.
+func AcceptLinkTransfer(transfer *LinkTransfer, acceptedBy int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// The recipient may have left the workspace since the request
+		if TransferLeavesWorkspace(tx, *transfer) {
+			var requester Membership
+			err := tx.Where("workspace_id = ? AND user_id = ?", transfer.FromWorkspaceID, transfer.RequestedBy).First(&requester).Error
+			if err != nil || !RoleAtLeast(requester.Role, RoleAdmin) {
+				return ErrTransferNotAllowed
+			}
+		}
+
+		for i := range transfer.Items {
+			item := &transfer.Items[i]
+
+			var url URL
+			if err := tx.Preload("Tags").First(&url, item.URLID).Error; err != nil {
+				return ErrTransferLinksChanged
+			}
+			if url.WorkspaceID != transfer.FromWorkspaceID {
+				return ErrTransferLinksChanged
+			}
+
+			userID, workspaceID := url.UserID, url.WorkspaceID
+			if transfer.ToWorkspaceID != nil {
+				workspaceID = *transfer.ToWorkspaceID
+			} else {
+				userID = *transfer.ToUserID
+				if err := tx.Where("workspace_id = ? AND user_id = ?", url.WorkspaceID, userID).First(&Membership{}).Error; err != nil {
+					var personal Workspace
+					if err := tx.Where("created_by = ? AND personal = ?", userID, true).First(&personal).Error; err != nil {
+						return err
+					}
+					workspaceID = personal.ID
+				}
+			}
+
+			updates := map[string]interface{}{"user_id": userID, "workspace_id": workspaceID}
+			if workspaceID != url.WorkspaceID {
+				updates["folder_id"] = nil
+
+				tags := make([]Tag, 0, len(url.Tags))
+				for _, old := range url.Tags {
+					tag := Tag{UserID: acceptedBy, WorkspaceID: workspaceID, Name: old.Name, Color: old.Color}
+					if err := tx.Where(Tag{WorkspaceID: workspaceID, Name: old.Name}).FirstOrCreate(&tag).Error; err != nil {
+						return err
+					}
+					tags = append(tags, tag)
+				}
+				if err := tx.Model(&url).Association("Tags").Replace(tags); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Model(&URL{}).Where("id = ?", url.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+
+			item.PreviousUserID = url.UserID
+			item.PreviousWorkspaceID = url.WorkspaceID
+			item.NewUserID = &userID
+			item.NewWorkspaceID = &workspaceID
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		transfer.Status = TransferAccepted
+		transfer.RespondedBy = &acceptedBy
+		transfer.RespondedAt = &now
+		return tx.Omit("Items").Save(transfer).Error
+	})
+}
+
+// TransferLeavesWorkspace reports whether accepting the transfer moves the
+// links out of their workspace: always for transfers to a workspace, and
+// for transfers to a user who isn't a member of it.
+func TransferLeavesWorkspace(tx *gorm.DB, transfer LinkTransfer) bool {
+	if transfer.ToWorkspaceID != nil {
+		return *transfer.ToWorkspaceID != transfer.FromWorkspaceID
+	}
+	var count int64
+	tx.Model(&Membership{}).Where("workspace_id = ? AND user_id = ?", transfer.FromWorkspaceID, *transfer.ToUserID).Count(&count)
+	return count == 0
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAcceptLinkTransferLeavingWorkspace(t *testing.T) {
+	setupTestDB(t)
+	// Workspace 1 is shared by users 1 (admin) and 2 (editor); user 3 has only
+	// a personal workspace.
+	workspaces := []Workspace{
+		{Name: "Team", CreatedBy: 1},
+		{Name: "Personal", CreatedBy: 3, Personal: true},
+	}
+	DB.Create(&workspaces)
+	DB.Create(&[]Membership{
+		{WorkspaceID: 1, UserID: 1, Role: RoleAdmin},
+		{WorkspaceID: 1, UserID: 2, Role: RoleEditor},
+		{WorkspaceID: 2, UserID: 3, Role: RoleOwner},
+	})
+
+	member, outsider := 2, 3
+	tests := []struct {
+		name        string
+		requestedBy int
+		toUserID    *int
+		wantLeaves  bool
+		wantErr     error
+	}{
+		{"editor to a member", 2, &member, false, nil},
+		{"editor to an outsider", 2, &outsider, true, ErrTransferNotAllowed},
+		{"admin to an outsider", 1, &outsider, true, nil},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := URL{ShortCode: "t" + string(rune('a'+i)), OriginalURL: "https://example.com", UserID: 1, WorkspaceID: 1}
+			DB.Create(&url)
+			transfer := LinkTransfer{
+				FromWorkspaceID: 1,
+				ToUserID:        tt.toUserID,
+				Status:          TransferPending,
+				RequestedBy:     tt.requestedBy,
+				Items:           []LinkTransferItem{{URLID: url.ID, PreviousUserID: 1, PreviousWorkspaceID: 1}},
+			}
+			DB.Create(&transfer)
+
+			if got := TransferLeavesWorkspace(DB, transfer); got != tt.wantLeaves {
+				t.Errorf("TransferLeavesWorkspace() = %v, want %v", got, tt.wantLeaves)
+			}
+			err := AcceptLinkTransfer(&transfer, *tt.toUserID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AcceptLinkTransfer() error = %v, want %v", err, tt.wantErr)
+			}
+
+			DB.First(&url, url.ID)
+			wantWorkspace := 1
+			if tt.wantErr == nil && tt.wantLeaves {
+				wantWorkspace = 2
+			}
+			if url.WorkspaceID != wantWorkspace {
+				t.Errorf("workspace = %d, want %d", url.WorkspaceID, wantWorkspace)
+			}
+		})
+	}
+}
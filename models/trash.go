@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Deleted links stay in the trash for the retention of their workspace
+// (30 days by default) before they are purged for good.
+const MaxTrashRetentionDays = 365
+
+// PurgeAt returns when a link in the trash will be purged.
+func (u URL) PurgeAt(retentionDays int) *time.Time {
+	if !u.DeletedAt.Valid {
+		return nil
+	}
+	purgeAt := u.DeletedAt.Time.AddDate(0, 0, retentionDays)
+	return &purgeAt
+}
+
+// PurgeURL permanently deletes a link together with its clicks, conversions,
+// rollups, revisions, schedules, rules, variants, bio page blocks and tag
+// assignments, which frees its short code.
+func PurgeURL(tx *gorm.DB, urlID int) error {
+	for _, model := range []interface{}{&Click{}, &Conversion{}, &HourlyClickRollup{}, &DailyClickRollup{}, &URLRevision{}, &URLSchedule{}, &URLRule{}, &URLVariant{}, &PostBlock{}, &HealthCheck{}, &Report{}} {
+		if err := tx.Where("url_id = ?", urlID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Exec("DELETE FROM url_tags WHERE url_id = ?", urlID).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&URL{}, urlID).Error
+}
+
+// PurgeTrashedURLs purges the links that stayed in the trash longer than the
+// retention of their workspace and returns how many were removed.
+func PurgeTrashedURLs() (int64, error) {
+	now := time.Now()
+
+	var retentions []int
+	if err := DB.Model(&Workspace{}).Distinct().Pluck("trash_retention_days", &retentions).Error; err != nil {
+		return 0, err
+	}
+
+	var expired []URL
+	for _, days := range retentions {
+		var urls []URL
+		if err := DB.Unscoped().Select("id", "workspace_id", "short_code").
+			Where("workspace_id IN (SELECT id FROM workspaces WHERE trash_retention_days = ?)", days).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", now.AddDate(0, 0, -days)).
+			Find(&urls).Error; err != nil {
+			return 0, err
+		}
+		expired = append(expired, urls...)
+	}
+
+	var purged int64
+	for _, url := range expired {
+		if err := DB.Transaction(func(tx *gorm.DB) error {
+			return PurgeURL(tx, url.ID)
+		}); err != nil {
+			return purged, err
+		}
+		purged++
+
+		workspaceID := url.WorkspaceID
+		RecordAuditEvent(&AuditEvent{
+			WorkspaceID: &workspaceID,
+			Action:      AuditURLPurged,
+			TargetType:  "url",
+			TargetID:    url.ID,
+			Details:     fmt.Sprintf("short code %s released after the trash retention", url.ShortCode),
+		})
+	}
+	return purged, nil
+}
@@ -3,17 +3,68 @@ package models
 import (
 	"crypto/rand"
 	"encoding/base64"
+	neturl "net/url"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type URL struct {
-	ID          int       `json:"id" gorm:"primary_key"`
-	OriginalURL string    `json:"original_url" gorm:"not null"`
-	ShortCode   string    `json:"short_code" gorm:"unique;not null"`
-	ClickCount  int       `json:"click_count" gorm:"default:0"`
-	UserID      int       `json:"user_id" gorm:"not null"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int        `json:"id" gorm:"primary_key"`
+	OriginalURL string     `json:"original_url" gorm:"not null"`
+	ShortCode   string     `json:"short_code" gorm:"unique;not null"`
+	Title       string     `json:"title"`
+	Notes       string     `json:"notes"`
+	Domain      string     `json:"domain" gorm:"index"` // Host of OriginalURL, used for filtering
+	ClickCount  int        `json:"click_count" gorm:"default:0"`
+	UserID      int        `json:"user_id" gorm:"not null"` // Creator of the link
+	WorkspaceID int        `json:"workspace_id" gorm:"index"`
+	FolderID    *int       `json:"folder_id" gorm:"index"`
+	Tags        []Tag      `json:"tags" gorm:"many2many:url_tags;"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Disabled    bool       `json:"disabled" gorm:"default:false"` // Disabled links stop redirecting
+	// Blocked by an admin or by abuse reports. Unlike disabled links, only
+	// admins can unblock them, and visitors get a warning page.
+	Blocked          bool       `json:"blocked" gorm:"default:false;index"`
+	BlockedReason    string     `json:"blocked_reason"`
+	BlockedAt        *time.Time `json:"blocked_at"`
+	ActiveFrom       *time.Time `json:"active_from"`                            // The link redirects from this time on
+	FallbackURL      string     `json:"fallback_url"`                           // Where visitors go before the link is active
+	TrackConversions bool       `json:"track_conversions" gorm:"default:false"` // Pass the click id on as sl_cid
+	// UTM parameters added to the destination on redirect
+	UTMSource    string `json:"utm_source"`
+	UTMMedium    string `json:"utm_medium"`
+	UTMCampaign  string `json:"utm_campaign" gorm:"index"`
+	UTMTerm      string `json:"utm_term"`
+	UTMContent   string `json:"utm_content"`
+	ForwardQuery bool   `json:"forward_query" gorm:"default:false"` // Pass the short link's query string on
+	// Prefix links also match longer paths: /docs/intro for the code "docs".
+	// PathParams names the segments after the code for the destination
+	// template.
+	MatchPrefix bool       `json:"match_prefix" gorm:"default:false"`
+	PathParams  StringList `json:"path_params"`
+	// Deep links into the mobile apps
+	IOS     AppLink `json:"ios" gorm:"embedded;embeddedPrefix:ios_"`
+	Android AppLink `json:"android" gorm:"embedded;embeddedPrefix:android_"`
+	// Health of the destination, from the last checks of the monitor
+	HealthStatus     string     `json:"health_status" gorm:"default:'unknown';index"`
+	HealthFailures   int        `json:"health_failures" gorm:"default:0"` // Failed checks in a row
+	HealthStatusCode int        `json:"health_status_code"`
+	HealthCheckedAt  *time.Time `json:"health_checked_at"`
+	// Where the destination ends up after its redirects, and how many there
+	// are, as of the last time they were followed
+	FinalURL      string        `json:"final_url"`
+	RedirectHops  int           `json:"redirect_hops" gorm:"default:0"`
+	Schedules     []URLSchedule `json:"schedules" gorm:"foreignKey:URLID"`
+	Rules         []URLRule     `json:"rules" gorm:"foreignKey:URLID"`
+	Variants      []URLVariant  `json:"variants" gorm:"foreignKey:URLID"`
+	LastClickedAt *time.Time    `json:"last_clicked_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	// Set while the link is in the trash. Its short code stays reserved
+	// until the link is purged.
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
 func GenerateShortCode() string {
@@ -21,3 +72,17 @@ func GenerateShortCode() string {
 	rand.Read(b)
 	return base64.URLEncoding.EncodeToString(b)[:8]
 }
+
+// IsExpired reports whether the link has an expiry date that has passed.
+func (u URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// DomainOf returns the lower-cased host of a URL without "www.".
+func DomainOf(rawURL string) string {
+	parsed, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
@@ -1,13 +1,45 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	ID        int       `json:"id" gorm:"primary_key"`
 	Name      string    `json:"name"`
 	Username  string    `json:"username" gorm:"unique;not null"`
 	Email     string    `json:"email" gorm:"unique;not null"`
-	Password  string    `json:"-"` // Don't expose password in JSON responses
+	Password  string    `json:"-"`                             // Don't expose password in JSON responses
+	Timezone  string    `json:"timezone"`                      // IANA timezone used for analytics, e.g. Asia/Jakarta
+	IsAdmin   bool      `json:"is_admin" gorm:"default:false"` // Reviews abuse reports of every workspace
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// SyncAdmins makes the users with the given emails admins, and the other
+// users not. Emails are matched without regard to case.
+func SyncAdmins(emails []string) error {
+	var normalized []string
+	for _, email := range emails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			normalized = append(normalized, email)
+		}
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		demote := tx.Model(&User{}).Where("is_admin = ?", true)
+		if len(normalized) > 0 {
+			demote = demote.Where("LOWER(email) NOT IN ?", normalized)
+		}
+		if err := demote.Update("is_admin", false).Error; err != nil {
+			return err
+		}
+		if len(normalized) == 0 {
+			return nil
+		}
+		return tx.Model(&User{}).Where("LOWER(email) IN ?", normalized).Update("is_admin", true).Error
+	})
+}
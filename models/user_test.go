@@ -0,0 +1,60 @@
+package models
+
+import "testing"
+
+func TestSyncAdmins(t *testing.T) {
+	setupTestDB(t)
+	users := []User{
+		{Username: "alice", Email: "Alice@Example.com"},
+		{Username: "bob", Email: "bob@example.com", IsAdmin: true},
+		{Username: "carol", Email: "carol@example.com", IsAdmin: true},
+	}
+	if err := DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	isAdmin := func() map[string]bool {
+		var all []User
+		DB.Find(&all)
+		admins := map[string]bool{}
+		for _, user := range all {
+			admins[user.Username] = user.IsAdmin
+		}
+		return admins
+	}
+
+	tests := []struct {
+		name   string
+		emails []string
+		want   map[string]bool
+	}{
+		{
+			name:   "trims, ignores case and demotes unlisted admins",
+			emails: []string{" alice@example.com ", "CAROL@example.com", ""},
+			want:   map[string]bool{"alice": true, "bob": false, "carol": true},
+		},
+		{
+			name:   "unknown emails are ignored",
+			emails: []string{"carol@example.com", "nobody@example.com"},
+			want:   map[string]bool{"alice": false, "bob": false, "carol": true},
+		},
+		{
+			name:   "no emails demotes everyone",
+			emails: []string{""},
+			want:   map[string]bool{"alice": false, "bob": false, "carol": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SyncAdmins(tt.emails); err != nil {
+				t.Fatal(err)
+			}
+			got := isAdmin()
+			for username, want := range tt.want {
+				if got[username] != want {
+					t.Errorf("%s admin = %v, want %v", username, got[username], want)
+				}
+			}
+		})
+	}
+}
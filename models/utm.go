@@ -0,0 +1,52 @@
+package models
+
+import neturl "net/url"
+
+// UTMParams returns the link's UTM fields as query parameters, without the
+// empty ones.
+func (u URL) UTMParams() neturl.Values {
+	params := neturl.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   u.UTMSource,
+		"utm_medium":   u.UTMMedium,
+		"utm_campaign": u.UTMCampaign,
+		"utm_term":     u.UTMTerm,
+		"utm_content":  u.UTMContent,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	return params
+}
+
+// MergeQuery adds params to a destination URL. Parameters the destination
+// already has are kept as they are, and its own query string is left
+// untouched.
+func MergeQuery(destination string, params neturl.Values) string {
+	if len(params) == 0 {
+		return destination
+	}
+	parsed, err := neturl.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	existing := parsed.Query()
+	added := neturl.Values{}
+	for key, values := range params {
+		if !existing.Has(key) {
+			added[key] = values
+		}
+	}
+	if len(added) == 0 {
+		return destination
+	}
+
+	if parsed.RawQuery == "" {
+		parsed.RawQuery = added.Encode()
+	} else {
+		parsed.RawQuery += "&" + added.Encode()
+	}
+	return parsed.String()
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Limits of a link's A/B split
+const (
+	MaxURLVariants   = 10
+	MaxVariantWeight = 1000
+)
+
+// URLVariant is one destination of an A/B split. Each visitor is sent to one
+// variant with a probability proportional to its weight; a weight of 0 pauses
+// the variant.
+type URLVariant struct {
+	ID             int       `json:"id" gorm:"primary_key"`
+	URLID          int       `json:"url_id" gorm:"not null;index"`
+	Position       int       `json:"position" gorm:"not null"`
+	Name           string    `json:"name"`
+	DestinationURL string    `json:"destination_url" gorm:"not null"`
+	Weight         int       `json:"weight" gorm:"not null;default:1"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PickVariant returns the variant for a visitor key, spread over the variants
+// by weight. The same key always gets the same variant as long as the
+// weights don't change. Nil when the link has no variant with a weight.
+func (u URL) PickVariant(key uint64) *URLVariant {
+	total := 0
+	for _, variant := range u.Variants {
+		total += variant.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	point := int(key % uint64(total))
+	for i := range u.Variants {
+		if point < u.Variants[i].Weight {
+			return &u.Variants[i]
+		}
+		point -= u.Variants[i].Weight
+	}
+	return nil
+}
+
+// Significance compares the conversion rate of a variant with the control
+// using a two-proportion z-test. The p-value is two-sided.
+func Significance(controlConversions, controlClicks, conversions, clicks int64) (z, pValue float64) {
+	if controlClicks == 0 || clicks == 0 {
+		return 0, 1
+	}
+
+	p1 := float64(controlConversions) / float64(controlClicks)
+	p2 := float64(conversions) / float64(clicks)
+	pooled := float64(controlConversions+conversions) / float64(controlClicks+clicks)
+	stdErr := math.Sqrt(pooled * (1 - pooled) * (1/float64(controlClicks) + 1/float64(clicks)))
+	if stdErr == 0 {
+		return 0, 1
+	}
+
+	z = (p2 - p1) / stdErr
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPickVariant(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		want    []int // Visitors out of 10000 sent to each variant
+	}{
+		{"by weight", []int{1, 3, 6}, []int{1000, 3000, 6000}},
+		{"paused variant", []int{5, 0, 5}, []int{5000, 0, 5000}},
+		{"single variant", []int{7}, []int{10000}},
+		{"all paused", []int{0, 0}, []int{0, 0}},
+		{"no variants", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var link URL
+			for i, weight := range tt.weights {
+				link.Variants = append(link.Variants, URLVariant{ID: i + 1, Weight: weight})
+			}
+			got := make([]int, len(tt.weights))
+			for key := uint64(0); key < 10000; key++ {
+				variant := link.PickVariant(key)
+				if variant == nil {
+					continue
+				}
+				got[variant.ID-1]++
+				if again := link.PickVariant(key); again.ID != variant.ID {
+					t.Fatalf("key %d got variants %d and %d", key, variant.ID, again.ID)
+				}
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("visitors per variant = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSignificance(t *testing.T) {
+	tests := []struct {
+		name                         string
+		controlConversions, controls int64
+		conversions, clicks          int64
+		wantZ, wantP                 float64
+	}{
+		{"better variant", 100, 1000, 150, 1000, 3.3806, 0.000723},
+		{"worse variant", 150, 1000, 100, 1000, -3.3806, 0.000723},
+		{"small difference", 100, 1000, 110, 1000, 0.7294, 0.4657},
+		{"same rate", 50, 500, 100, 1000, 0, 1},
+		{"no conversions", 0, 1000, 0, 1000, 0, 1},
+		{"no control clicks", 0, 0, 10, 100, 0, 1},
+		{"no variant clicks", 10, 100, 0, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z, p := Significance(tt.controlConversions, tt.controls, tt.conversions, tt.clicks)
+			if math.Abs(z-tt.wantZ) > 0.0001 || math.Abs(p-tt.wantP) > 0.0001 {
+				t.Errorf("Significance() = %.4f, %.6f, want %.4f, %.6f", z, p, tt.wantZ, tt.wantP)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VisitorSalt is the random salt used to hash visitor identifiers on a given
+// UTC day. Salts are deleted once the day is over, so a stored visitor hash
+// can no longer be linked back to an IP address.
+type VisitorSalt struct {
+	ID        int       `json:"id" gorm:"primary_key"`
+	Day       time.Time `json:"day" gorm:"unique;not null"`
+	Salt      string    `json:"-" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	saltMu      sync.Mutex
+	currentSalt VisitorSalt
+)
+
+// HashVisitor derives the visitor hash for a click from the visitor's IP and
+// user agent with the salt of the day. The same visitor gets the same hash for
+// the whole day and an unrelated one the next day.
+func HashVisitor(ip, userAgent string, at time.Time) (string, error) {
+	salt, err := visitorSalt(DayBucket(at))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(salt + "|" + ip + "|" + userAgent))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NetworkOf returns the network an IP address belongs to: its /24 for IPv4
+// and its /48 for IPv6. Invalid addresses are returned as they are.
+func NetworkOf(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
+
+func visitorSalt(day time.Time) (string, error) {
+	saltMu.Lock()
+	defer saltMu.Unlock()
+
+	if currentSalt.Salt != "" && currentSalt.Day.Equal(day) {
+		return currentSalt.Salt, nil
+	}
+
+	var salt VisitorSalt
+	err := DB.Where("day = ?", day).First(&salt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		salt = VisitorSalt{Day: day, Salt: hex.EncodeToString(b)}
+		if err := DB.Create(&salt).Error; err != nil {
+			return "", err
+		}
+
+		// Forget the salts of previous days
+		DB.Where("day < ?", day).Delete(&VisitorSalt{})
+	} else if err != nil {
+		return "", err
+	}
+
+	currentSalt = salt
+	return salt.Salt, nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import "testing"
+
+func TestNetworkOf(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.7", "203.0.113.0/24"},
+		{"203.0.113.250", "203.0.113.0/24"},
+		{"::ffff:203.0.113.7", "203.0.113.0/24"},
+		{"2001:db8:abcd:12::1", "2001:db8:abcd::/48"},
+		{"not-an-ip", "not-an-ip"},
+	}
+	for _, tt := range tests {
+		if got := NetworkOf(tt.ip); got != tt.want {
+			t.Errorf("NetworkOf(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
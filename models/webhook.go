@@ -0,0 +1,117 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook events
+const (
+	EventLinkCreated   = "link.created"
+	EventLinkUpdated   = "link.updated"
+	EventLinkDeleted   = "link.deleted"
+	EventLinkRestored  = "link.restored"
+	EventLinkClicked   = "link.clicked"
+	EventLinkBroken    = "link.broken"    // The destination stopped responding
+	EventLinkRecovered = "link.recovered" // A broken destination works again
+	EventLinkBlocked   = "link.blocked"   // Blocked after abuse reports
+	EventLinkUnblocked = "link.unblocked"
+)
+
+var WebhookEvents = []string{EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkRestored, EventLinkClicked, EventLinkBroken, EventLinkRecovered, EventLinkBlocked, EventLinkUnblocked}
+
+type Webhook struct {
+	ID          int       `json:"id" gorm:"primary_key"`
+	UserID      int       `json:"user_id" gorm:"not null;index"`
+	WorkspaceID int       `json:"workspace_id" gorm:"index"`
+	TargetURL   string    `json:"target_url" gorm:"not null"`
+	Events      string    `json:"-" gorm:"not null"` // Comma-separated list of subscribed events
+	Secret      string    `json:"-" gorm:"not null"` // Used to sign payloads, only shown on creation
+	Active      bool      `json:"active"`            // No column default, which would turn an explicit false into true on create
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (w Webhook) EventList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+func (w Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is both the outbox entry and the delivery log of a single
+// event sent to a single webhook.
+type WebhookDelivery struct {
+	ID             int        `json:"id" gorm:"primary_key"`
+	WebhookID      int        `json:"webhook_id" gorm:"not null;index"`
+	EventID        string     `json:"event_id" gorm:"not null;index"`
+	Event          string     `json:"event" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"not null"`
+	Status         string     `json:"status" gorm:"not null;index;default:'pending'"`
+	Attempts       int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// EnqueueWebhookEvent stores a delivery in the outbox for every active
+// webhook of the workspace subscribed to the event. The dispatcher sends them.
+func EnqueueWebhookEvent(workspaceID int, event string, data interface{}) error {
+	var webhooks []Webhook
+	if err := DB.Where("workspace_id = ? AND active = ?", workspaceID, true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+
+	eventID := uuid.NewString()
+	now := time.Now()
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         eventID,
+		"event":      event,
+		"created_at": now,
+		"data":       data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		delivery := WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        DeliveryPending,
+			NextAttemptAt: now,
+		}
+		if err := DB.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
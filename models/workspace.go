@@ -0,0 +1,136 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Workspace roles, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{RoleOwner: 4, RoleAdmin: 3, RoleEditor: 2, RoleViewer: 1}
+
+// ValidRole reports whether role is one of the workspace roles.
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAtLeast reports whether role grants at least the permissions of min.
+func RoleAtLeast(role, min string) bool {
+	return roleRanks[role] >= roleRanks[min]
+}
+
+// RolesAtLeast lists the roles ranking at least min, for queries.
+func RolesAtLeast(min string) []string {
+	var roles []string
+	for role := range roleRanks {
+		if RoleAtLeast(role, min) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// Workspace owns links, tags, folders and webhooks. Every user has a personal
+// workspace; team workspaces are shared through memberships.
+type Workspace struct {
+	ID                 int       `json:"id" gorm:"primary_key"`
+	Name               string    `json:"name" gorm:"not null"`
+	Personal           bool      `json:"personal" gorm:"default:false"`
+	CreatedBy          int       `json:"created_by" gorm:"not null;index"`
+	AuditRetentionDays int       `json:"audit_retention_days" gorm:"not null;default:365"`
+	TrashRetentionDays int       `json:"trash_retention_days" gorm:"not null;default:30"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type Membership struct {
+	ID          int       `json:"id" gorm:"primary_key"`
+	WorkspaceID int       `json:"workspace_id" gorm:"not null;uniqueIndex:idx_membership_workspace_user"`
+	UserID      int       `json:"user_id" gorm:"not null;uniqueIndex:idx_membership_workspace_user;index"`
+	Role        string    `json:"role" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Invitation lets someone join a workspace. The token is sent to the invited
+// email address and can be accepted once by the user with that email.
+type Invitation struct {
+	ID          int        `json:"id" gorm:"primary_key"`
+	WorkspaceID int        `json:"workspace_id" gorm:"not null;index"`
+	Email       string     `json:"email" gorm:"not null"`
+	Role        string     `json:"role" gorm:"not null"`
+	Token       string     `json:"-" gorm:"uniqueIndex;not null"`
+	InvitedBy   int        `json:"invited_by" gorm:"not null"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// InvitationTTL is how long an invitation can be accepted.
+const InvitationTTL = 7 * 24 * time.Hour
+
+func NewInvitationToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// FindMembership returns the user's membership in a workspace.
+func FindMembership(workspaceID, userID int) (Membership, error) {
+	var membership Membership
+	err := DB.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&membership).Error
+	return membership, err
+}
+
+// PersonalWorkspaceID returns the ID of the user's personal workspace.
+func PersonalWorkspaceID(userID int) (int, error) {
+	var workspace Workspace
+	err := DB.Where("created_by = ? AND personal = ?", userID, true).First(&workspace).Error
+	return workspace.ID, err
+}
+
+// CreateWorkspace creates a workspace with the user as its owner.
+func CreateWorkspace(db *gorm.DB, workspace *Workspace) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Membership{WorkspaceID: workspace.ID, UserID: workspace.CreatedBy, Role: RoleOwner}).Error
+	})
+}
+
+// CreatePersonalWorkspace creates the workspace a user's own links live in.
+func CreatePersonalWorkspace(db *gorm.DB, user User) (Workspace, error) {
+	workspace := Workspace{Name: user.Name, Personal: true, CreatedBy: user.ID}
+	if workspace.Name == "" {
+		workspace.Name = user.Username
+	}
+	err := CreateWorkspace(db, &workspace)
+	return workspace, err
+}
+
+// migratePersonalWorkspaces gives every user without one a personal workspace
+// and moves the links, tags, folders and webhooks that don't belong to a
+// workspace yet into it.
+func migratePersonalWorkspaces(db *gorm.DB) {
+	var users []User
+	db.Where("id NOT IN (SELECT created_by FROM workspaces WHERE personal = ?)", true).Find(&users)
+
+	for _, user := range users {
+		CreatePersonalWorkspace(db, user)
+	}
+
+	for _, table := range []string{"urls", "tags", "folders", "webhooks"} {
+		db.Exec("UPDATE "+table+" SET workspace_id = (SELECT id FROM workspaces WHERE workspaces.created_by = "+table+".user_id AND workspaces.personal = ?) WHERE workspace_id IS NULL OR workspace_id = 0", true)
+	}
+}
@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"backend-go/models"
+	"backend-go/notify"
+	"backend-go/redirects"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	neturl "net/url"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	batchSize    = 200
+	maxErrorText = 500
+	probeTimeout = 30 * time.Second // For the whole redirect chain
+)
+
+// Monitor checks the destinations of the active links and flags the ones
+// that answer with an error or don't answer at all.
+type Monitor struct {
+	Resolver    *redirects.Resolver
+	Interval    time.Duration // Time between two rounds of checks
+	Concurrency int           // Probes running at once
+	HostDelay   time.Duration // Minimum time between two probes of one host
+	Notifier    notify.Notifier
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{
+		Resolver:    redirects.NewResolver(redirects.DefaultOwnHosts),
+		Interval:    6 * time.Hour,
+		Concurrency: 8,
+		HostDelay:   2 * time.Second,
+		Notifier:    notify.Default,
+	}
+}
+
+// Start checks every link right away, then once per interval.
+func (m *Monitor) Start() {
+	go func() {
+		for {
+			m.CheckAll()
+			time.Sleep(m.Interval)
+		}
+	}()
+}
+
+// CheckAll probes the destination of every active link once. Blocked links
+// are left alone.
+func (m *Monitor) CheckAll() {
+	gate := newHostGate(m.HostDelay)
+	now := time.Now()
+
+	var links []models.URL
+	err := models.DB.Where("disabled = ? AND blocked = ?", false, false).
+		Where("(expires_at IS NULL OR expires_at > ?)", now).
+		Where("(active_from IS NULL OR active_from <= ?)", now).
+		FindInBatches(&links, batchSize, func(tx *gorm.DB, batch int) error {
+			m.checkBatch(links, gate)
+			return nil
+		}).Error
+	if err != nil {
+		log.Printf("Failed to check links: %v", err)
+	}
+
+	if _, err := models.PurgeHealthChecks(); err != nil {
+		log.Printf("Failed to purge health checks: %v", err)
+	}
+}
+
+// checkBatch probes the links concurrently. Links sharing a destination
+// are probed once.
+func (m *Monitor) checkBatch(links []models.URL, gate *hostGate) {
+	byDestination := map[string][]int{}
+	for i, link := range links {
+		if destination := probeTarget(link); destination != "" {
+			byDestination[destination] = append(byDestination[destination], i)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	checks := make(map[string]models.HealthCheck, len(byDestination))
+	slots := make(chan struct{}, max(m.Concurrency, 1))
+
+	for destination, indexes := range byDestination {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(destination string, link models.URL) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			gate.wait(models.DomainOf(destination))
+			check := m.Probe(link)
+			mu.Lock()
+			checks[destination] = check
+			mu.Unlock()
+		}(destination, links[indexes[0]])
+	}
+	wg.Wait()
+
+	// Stored one by one, SQLite doesn't like concurrent writers
+	for destination, indexes := range byDestination {
+		for _, i := range indexes {
+			check := checks[destination]
+			m.record(&links[i], check)
+		}
+	}
+}
+
+// record stores a check and notifies the link's owner when the link breaks
+// or recovers. Checks of links edited meanwhile are dropped, they probed the
+// old destination.
+func (m *Monitor) record(link *models.URL, check models.HealthCheck) {
+	previous, err := models.RecordHealthCheck(link, &check)
+	if errors.Is(err, models.ErrLinkChanged) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to save health check of link %d: %v", link.ID, err)
+		return
+	}
+	if m.Notifier == nil || previous == link.HealthStatus {
+		return
+	}
+
+	notification := notify.Notification{
+		Link: *link,
+		Data: map[string]interface{}{
+			"status_code":      check.StatusCode,
+			"response_time_ms": check.ResponseTimeMs,
+			"error":            check.Error,
+			"checked_at":       check.CheckedAt,
+		},
+	}
+	switch {
+	case link.HealthStatus == models.HealthBroken:
+		notification.Event = models.EventLinkBroken
+		notification.Message = "The destination is broken: " + check.Error
+	case previous == models.HealthBroken:
+		notification.Event = models.EventLinkRecovered
+		notification.Message = fmt.Sprintf("The destination works again (status %d)", check.StatusCode)
+	default:
+		return
+	}
+	if err := m.Notifier.Notify(notification); err != nil {
+		log.Printf("Failed to notify about link %d: %v", link.ID, err)
+	}
+}
+
+// Probe follows the redirects of a link's destination and reports where
+// they lead and how the final URL answered.
+func (m *Monitor) Probe(link models.URL) models.HealthCheck {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	chain, err := m.Resolver.Resolve(ctx, link)
+
+	final := chain.Final()
+	check := models.HealthCheck{
+		StatusCode:     final.StatusCode,
+		ResponseTimeMs: time.Since(start).Milliseconds(),
+		FinalURL:       final.URL,
+		RedirectHops:   chain.Length(),
+		Healthy:        err == nil && final.StatusCode < 400,
+		CheckedAt:      start,
+	}
+	if err != nil {
+		check.Error = truncate(err.Error(), maxErrorText)
+	} else if final.StatusCode >= 400 {
+		check.Error = fmt.Sprintf("responded with status %d", final.StatusCode)
+	}
+	return check
+}
+
+// probeTarget returns the URL to probe for a link, with the placeholders of
+// templated destinations left empty, or "" when it isn't a web URL.
+func probeTarget(link models.URL) string {
+	destination := redirects.StartURL(link)
+	parsed, err := neturl.Parse(destination)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ""
+	}
+	return destination
+}
+
+// hostGate spaces out the probes of each host.
+type hostGate struct {
+	delay time.Duration
+	mu    sync.Mutex
+	next  map[string]time.Time
+}
+
+func newHostGate(delay time.Duration) *hostGate {
+	return &hostGate{delay: delay, next: map[string]time.Time{}}
+}
+
+// wait blocks until the host may be probed again, and reserves that slot.
+func (g *hostGate) wait(host string) {
+	g.mu.Lock()
+	at := time.Now()
+	if next, ok := g.next[host]; ok && next.After(at) {
+		at = next
+	}
+	g.next[host] = at.Add(g.delay)
+	g.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"backend-go/models"
+	"backend-go/notify"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	events []string
+}
+
+func (r *recordingNotifier) Notify(n notify.Notification) error {
+	r.events = append(r.events, n.Event)
+	return nil
+}
+
+func TestRecordSkipsEditedLinks(t *testing.T) {
+	t.Chdir(t.TempDir())
+	models.ConnectDB()
+	t.Cleanup(func() {
+		if sqlDB, err := models.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	link := models.URL{ShortCode: "edited", OriginalURL: "https://old.example.com", HealthStatus: models.HealthHealthy, HealthFailures: 1}
+	models.DB.Create(&link)
+	notifier := &recordingNotifier{}
+	m := &Monitor{Notifier: notifier}
+
+	// The owner fixes the destination while the old one is being probed
+	models.DB.Model(&models.URL{}).Where("id = ?", link.ID).Updates(map[string]interface{}{"original_url": "https://new.example.com", "health_status": models.HealthUnknown, "health_failures": 0})
+	m.record(&link, models.HealthCheck{StatusCode: 404, Error: "responded with status 404", CheckedAt: time.Now()})
+
+	if len(notifier.events) != 0 {
+		t.Errorf("notified %v, want nothing", notifier.events)
+	}
+	var stored models.URL
+	models.DB.First(&stored, link.ID)
+	if stored.HealthStatus != models.HealthUnknown || stored.HealthFailures != 0 {
+		t.Errorf("health = %q after %d failures, want unknown after 0", stored.HealthStatus, stored.HealthFailures)
+	}
+
+	// Checks of the current destination still count
+	m.record(&stored, models.HealthCheck{StatusCode: 404, Error: "responded with status 404", CheckedAt: time.Now()})
+	m.record(&stored, models.HealthCheck{StatusCode: 404, Error: "responded with status 404", CheckedAt: time.Now()})
+	if len(notifier.events) != 1 || notifier.events[0] != models.EventLinkBroken {
+		t.Errorf("notified %v, want [%s]", notifier.events, models.EventLinkBroken)
+	}
+}
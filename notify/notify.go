@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"backend-go/models"
+	"log"
+)
+
+// Notification tells the owner of a link about something that happened to
+// it, like its destination going down.
+type Notification struct {
+	Event   string // A webhook event, e.g. models.EventLinkBroken
+	Link    models.URL
+	Message string
+	Data    map[string]interface{} // More details, sent along with the link
+}
+
+// Notifier delivers notifications. Set Default to change where they go.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// Default sends notifications to the log and to the webhooks of the link's
+// workspace.
+var Default Notifier = Multi{LogNotifier{}, WebhookNotifier{}}
+
+// LogNotifier writes notifications to the server log.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(n Notification) error {
+	log.Printf("[%s] link %d (%s): %s", n.Event, n.Link.ID, n.Link.ShortCode, n.Message)
+	return nil
+}
+
+// WebhookNotifier queues the notification for the webhooks of the link's
+// workspace subscribed to its event.
+type WebhookNotifier struct{}
+
+func (WebhookNotifier) Notify(n Notification) error {
+	data := map[string]interface{}{
+		"id":           n.Link.ID,
+		"original_url": n.Link.OriginalURL,
+		"short_code":   n.Link.ShortCode,
+		"workspace_id": n.Link.WorkspaceID,
+		"message":      n.Message,
+	}
+	for key, value := range n.Data {
+		data[key] = value
+	}
+	return models.EnqueueWebhookEvent(n.Link.WorkspaceID, n.Event, data)
+}
+
+// Multi sends each notification to every notifier in turn, and returns the
+// first error.
+type Multi []Notifier
+
+func (m Multi) Notify(n Notification) error {
+	var first error
+	for _, notifier := range m {
+		if err := notifier.Notify(n); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,235 @@
+package redirects
+
+import (
+	"backend-go/models"
+	"backend-go/safehttp"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLoop             = errors.New("the destination redirects in a loop")
+	ErrTooManyRedirects = errors.New("the destination redirects too many times")
+)
+
+// DefaultOwnHosts are the hosts serving our short links when SHORT_DOMAINS
+// isn't set.
+var DefaultOwnHosts = []string{"electric-hideously-drake.ngrok-free.app", "localhost", "127.0.0.1"}
+
+// Hop is one URL of a redirect chain and how it answered.
+type Hop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`       // 0 when unreachable
+	Own        bool   `json:"own,omitempty"`     // One of our short links, resolved without a request
+	Error      string `json:"error,omitempty"`   // Why the chain stopped here
+	LinkID     int    `json:"link_id,omitempty"` // The short link of an own hop
+}
+
+// Chain is the path from a link's destination to where it ends up.
+type Chain struct {
+	Hops []Hop `json:"hops"`
+}
+
+// Length is the number of redirects followed.
+func (c Chain) Length() int {
+	return max(len(c.Hops)-1, 0)
+}
+
+// Final returns the last hop of the chain.
+func (c Chain) Final() Hop {
+	if len(c.Hops) == 0 {
+		return Hop{}
+	}
+	return c.Hops[len(c.Hops)-1]
+}
+
+// ThroughOwnHosts reports whether the chain passes through our short links.
+func (c Chain) ThroughOwnHosts() bool {
+	for _, hop := range c.Hops {
+		if hop.Own {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver follows the redirects of link destinations. Redirects into our
+// own hosts are followed by looking the short link up, so chains through
+// our links and loops back into them are seen without requesting ourselves.
+type Resolver struct {
+	Client   *http.Client // Its CheckRedirect is ignored, the resolver follows redirects itself
+	MaxHops  int
+	OwnHosts map[string]bool
+}
+
+func NewResolver(ownHosts []string) *Resolver {
+	hosts := map[string]bool{}
+	for _, host := range ownHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts[host] = true
+		}
+	}
+	return &Resolver{
+		Client:   safehttp.NewClient(10 * time.Second),
+		MaxHops:  10,
+		OwnHosts: hosts,
+	}
+}
+
+// Resolve follows the destination of a link until it stops redirecting.
+// The link may not be saved yet: redirects back to its short code use its
+// destination as given. The chain so far is returned with the error.
+func (r *Resolver) Resolve(ctx context.Context, link models.URL) (Chain, error) {
+	client := *r.Client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	var chain Chain
+	seen := map[string]bool{}
+	current := StartURL(link)
+
+	for {
+		if seen[current] {
+			chain.Hops = append(chain.Hops, Hop{URL: current, Error: ErrLoop.Error()})
+			return chain, ErrLoop
+		}
+		seen[current] = true
+		if len(chain.Hops) > r.MaxHops {
+			chain.Hops = append(chain.Hops, Hop{URL: current, Error: ErrTooManyRedirects.Error()})
+			return chain, ErrTooManyRedirects
+		}
+
+		parsed, err := neturl.Parse(current)
+		if err != nil {
+			chain.Hops = append(chain.Hops, Hop{URL: current, Error: err.Error()})
+			return chain, err
+		}
+
+		var hop Hop
+		var next string
+		if r.OwnHosts[strings.ToLower(parsed.Hostname())] {
+			hop, next = resolveOwn(link, parsed)
+		} else {
+			hop, next, err = r.request(ctx, &client, parsed)
+			if err != nil {
+				hop.Error = err.Error()
+				chain.Hops = append(chain.Hops, hop)
+				return chain, err
+			}
+		}
+		chain.Hops = append(chain.Hops, hop)
+		if next == "" {
+			return chain, nil
+		}
+		current = next
+	}
+}
+
+// StartURL is where a link sends visitors by default, with the placeholders
+// of templated destinations left empty.
+func StartURL(link models.URL) string {
+	return link.ExpandDestination(link.OriginalURL, models.LinkPath{})
+}
+
+// resolveOwn finds where one of our short links sends visitors, without a
+// request. Links that don't redirect end the chain with the status a
+// visitor would get.
+func resolveOwn(self models.URL, target *neturl.URL) (Hop, string) {
+	hop := Hop{URL: target.String(), Own: true}
+
+	link, segments, err := models.FindLinkByPath(target.EscapedPath())
+	if selfSegments, ok := matchSelf(self, target); ok && (err != nil || link.ID == self.ID || len(link.ShortCode) <= len(self.ShortCode)) {
+		// The link being saved, with its new destination
+		link, segments, err = self, selfSegments, nil
+	} else if err == nil && self.ID != 0 && link.ID == self.ID {
+		// The old short code of the link being saved
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		hop.StatusCode = http.StatusNotFound
+		return hop, ""
+	}
+
+	hop.LinkID = link.ID
+	if link.Disabled || link.IsExpired(time.Now()) {
+		hop.StatusCode = http.StatusGone
+		return hop, ""
+	}
+	hop.StatusCode = http.StatusFound
+	return hop, link.ExpandDestination(link.OriginalURL, models.LinkPath{Segments: segments, Query: target.Query()})
+}
+
+// matchSelf reports whether a URL points at the link being saved, and
+// returns the path segments after its short code.
+func matchSelf(self models.URL, target *neturl.URL) ([]string, bool) {
+	path := strings.Trim(target.EscapedPath(), "/")
+	if self.ShortCode == "" {
+		return nil, false
+	}
+	if path == self.ShortCode {
+		return nil, true
+	}
+	rest, ok := strings.CutPrefix(path, self.ShortCode+"/")
+	if !ok || !self.MatchPrefix {
+		return nil, false
+	}
+	segments, err := models.LinkPathSegments(rest)
+	if err != nil {
+		return nil, false
+	}
+	return segments, true
+}
+
+// request asks a destination how it answers, with HEAD and then GET for
+// servers that don't implement HEAD. It returns the next URL when the answer
+// is a redirect.
+func (r *Resolver) request(ctx context.Context, client *http.Client, target *neturl.URL) (Hop, string, error) {
+	hop := Hop{URL: target.String()}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		// Apps and other schemes end the chain, there is nothing to request
+		return hop, "", nil
+	}
+
+	resp, err := r.do(ctx, client, http.MethodHead, target)
+	if err != nil || resp.StatusCode >= 400 {
+		resp, err = r.do(ctx, client, http.MethodGet, target)
+	}
+	if err != nil {
+		return hop, "", err
+	}
+	hop.StatusCode = resp.StatusCode
+
+	location := resp.Header.Get("Location")
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+		return hop, "", nil
+	}
+	next, err := target.Parse(location)
+	if err != nil {
+		return hop, "", err
+	}
+	return hop, next.String(), nil
+}
+
+func (r *Resolver) do(ctx context.Context, client *http.Client, method string, target *neturl.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "backend-url-shortener-monitor/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return resp, nil
+}
@@ -0,0 +1,136 @@
+package redirects
+
+import (
+	"backend-go/models"
+	"backend-go/safehttp"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newDestination serves redirects from a path to another URL. Paths not in
+// redirects answer 200, and HEAD requests to noHead answer 405.
+func newDestination(t *testing.T, redirects map[string]string, noHead string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == noHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if location, ok := redirects[r.URL.Path]; ok {
+			http.Redirect(w, r, location, http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	models.ConnectDB()
+	t.Cleanup(func() {
+		if sqlDB, err := models.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+func TestResolve(t *testing.T) {
+	setupTestDB(t)
+
+	srv := newDestination(t, map[string]string{
+		"/a":        "/b",
+		"/b":        "/c",
+		"/loop-a":   "/loop-b",
+		"/loop-b":   "/loop-a",
+		"/1":        "/2",
+		"/2":        "/3",
+		"/3":        "/4",
+		"/no-head":  "/c",
+		"/to-own":   "http://short.test/other",
+		"/to-off":   "http://short.test/off",
+		"/to-gone":  "http://short.test/missing",
+		"/to-self":  "http://short.test/self",
+		"/to-param": "http://short.test/prefix/x/y",
+	}, "/no-head")
+
+	models.DB.Create(&models.URL{ShortCode: "other", OriginalURL: srv.URL + "/c"})
+	models.DB.Create(&models.URL{ShortCode: "off", OriginalURL: srv.URL + "/c", Disabled: true})
+	models.DB.Create(&models.URL{ShortCode: "prefix", OriginalURL: srv.URL + "/base", MatchPrefix: true})
+
+	tests := []struct {
+		name     string
+		link     models.URL
+		want     []int // Status code of each hop
+		wantErr  error
+		wantOwn  bool
+		finalURL string
+	}{
+		{"no redirect", models.URL{OriginalURL: srv.URL + "/c"}, []int{200}, nil, false, srv.URL + "/c"},
+		{"chain", models.URL{OriginalURL: srv.URL + "/a"}, []int{302, 302, 200}, nil, false, srv.URL + "/c"},
+		{"loop", models.URL{OriginalURL: srv.URL + "/loop-a"}, []int{302, 302, 0}, ErrLoop, false, srv.URL + "/loop-a"},
+		{"too many redirects", models.URL{OriginalURL: srv.URL + "/1"}, []int{302, 302, 302, 0}, ErrTooManyRedirects, false, srv.URL + "/4"},
+		{"GET when HEAD fails", models.URL{OriginalURL: srv.URL + "/no-head"}, []int{302, 200}, nil, false, srv.URL + "/c"},
+		{"through our short link", models.URL{OriginalURL: srv.URL + "/to-own"}, []int{302, 302, 200}, nil, true, srv.URL + "/c"},
+		{"into a disabled short link", models.URL{OriginalURL: srv.URL + "/to-off"}, []int{302, 410}, nil, true, "http://short.test/off"},
+		{"into a missing short link", models.URL{OriginalURL: srv.URL + "/to-gone"}, []int{302, 404}, nil, true, "http://short.test/missing"},
+		{"back into the link being saved", models.URL{ShortCode: "self", OriginalURL: srv.URL + "/to-self"}, []int{302, 302, 0}, ErrLoop, true, srv.URL + "/to-self"},
+		{"into a prefix link", models.URL{OriginalURL: srv.URL + "/to-param"}, []int{302, 302, 200}, nil, true, srv.URL + "/base/x/y"},
+		{"other schemes", models.URL{OriginalURL: "mailto:someone@example.com"}, []int{0}, nil, false, "mailto:someone@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewResolver([]string{"Short.test "})
+			r.Client = srv.Client()
+			r.MaxHops = 2
+
+			chain, err := r.Resolve(context.Background(), tt.link)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+			}
+			var got []int
+			for _, hop := range chain.Hops {
+				got = append(got, hop.StatusCode)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("hops = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("hops = %v, want %v", got, tt.want)
+				}
+			}
+			if chain.Length() != len(tt.want)-1 {
+				t.Errorf("Length() = %d, want %d", chain.Length(), len(tt.want)-1)
+			}
+			if chain.ThroughOwnHosts() != tt.wantOwn {
+				t.Errorf("ThroughOwnHosts() = %v, want %v", chain.ThroughOwnHosts(), tt.wantOwn)
+			}
+			if final := chain.Final(); final.URL != tt.finalURL {
+				t.Errorf("Final().URL = %q, want %q", final.URL, tt.finalURL)
+			}
+			if tt.wantErr != nil && chain.Final().Error == "" {
+				t.Error("the last hop doesn't say why the chain stopped")
+			}
+		})
+	}
+}
+
+func TestResolveRefusesPrivateAddresses(t *testing.T) {
+	srv := newDestination(t, nil, "")
+	r := NewResolver(nil)
+
+	chain, err := r.Resolve(context.Background(), models.URL{OriginalURL: srv.URL})
+	if !errors.Is(err, safehttp.ErrPrivateAddress) {
+		t.Fatalf("Resolve() error = %v, want %v", err, safehttp.ErrPrivateAddress)
+	}
+	if len(chain.Hops) != 1 || chain.Hops[0].Error == "" {
+		t.Errorf("hops = %+v, want one hop with the error", chain.Hops)
+	}
+}
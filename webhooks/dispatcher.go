@@ -0,0 +1,156 @@
+package webhooks
+
+import (
+	"backend-go/models"
+	"backend-go/safehttp"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	maxAttempts  = 8
+	baseBackoff  = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+	batchSize    = 50
+	maxErrorText = 500
+)
+
+// Dispatcher sends the pending deliveries of the outbox. Failed deliveries are
+// retried with exponential backoff until maxAttempts is reached. Its client
+// only connects to public addresses, so webhooks can't reach the internal
+// network.
+type Dispatcher struct {
+	Client   *http.Client
+	Interval time.Duration
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		Client:   safehttp.NewClient(10 * time.Second),
+		Interval: 5 * time.Second,
+	}
+}
+
+// Start polls the outbox in the background.
+func (d *Dispatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(d.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			d.DispatchPending()
+		}
+	}()
+}
+
+// DispatchPending sends every delivery that is due.
+func (d *Dispatcher) DispatchPending() {
+	var deliveries []models.WebhookDelivery
+	models.DB.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+		Order("id").
+		Limit(batchSize).
+		Find(&deliveries)
+
+	for i := range deliveries {
+		d.Deliver(&deliveries[i])
+	}
+}
+
+// Deliver makes one attempt at sending a delivery and records the outcome.
+func (d *Dispatcher) Deliver(delivery *models.WebhookDelivery) {
+	var webhook models.Webhook
+	if err := models.DB.First(&webhook, delivery.WebhookID).Error; err != nil {
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = "webhook not found"
+		models.DB.Save(delivery)
+		return
+	}
+	if !webhook.Active {
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = "webhook is inactive"
+		models.DB.Save(delivery)
+		return
+	}
+
+	delivery.Attempts++
+	statusCode, err := d.send(webhook, delivery)
+	delivery.LastStatusCode = statusCode
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = models.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.LastError = truncate(err.Error(), maxErrorText)
+		if delivery.Attempts >= maxAttempts {
+			delivery.Status = models.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+		}
+	}
+
+	if err := models.DB.Save(delivery).Error; err != nil {
+		log.Printf("Failed to save webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (d *Dispatcher) send(webhook models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.TargetURL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "backend-url-shortener-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Id", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign computes the HMAC-SHA256 signature of a payload. Receivers verify it by
+// signing "<X-Webhook-Timestamp>.<body>" with their secret.
+func Sign(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the next attempt: 30s, 1m, 2m, ... capped
+// at 6 hours.
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff << (attempts - 1)
+	if delay > maxBackoff || delay <= 0 {
+		return maxBackoff
+	}
+	return delay
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,212 @@
+package webhooks
+
+import (
+	"backend-go/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	models.ConnectDB()
+	t.Cleanup(func() {
+		if sqlDB, err := models.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// receiver records the requests it gets and answers them with the next
+// status of its list, repeating the last one.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+	status := r.statuses[min(len(r.requests), len(r.statuses))-1]
+	w.WriteHeader(status)
+}
+
+func newDelivery(t *testing.T, targetURL string, active bool) models.WebhookDelivery {
+	t.Helper()
+	webhook := models.Webhook{UserID: 1, WorkspaceID: 1, TargetURL: targetURL, Events: models.EventLinkCreated, Secret: "s3cret", Active: true}
+	if err := models.DB.Create(&webhook).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !active {
+		models.DB.Model(&webhook).Update("active", false)
+	}
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       "event-1",
+		Event:         models.EventLinkCreated,
+		Payload:       `{"id":"event-1","event":"link.created"}`,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := models.DB.Create(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+	return delivery
+}
+
+func TestDeliverSignsPayload(t *testing.T) {
+	setupTestDB(t)
+	recv := &receiver{statuses: []int{http.StatusNoContent}}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	delivery := newDelivery(t, srv.URL+"/hook", true)
+	d := &Dispatcher{Client: srv.Client()}
+	d.Deliver(&delivery)
+
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Fatalf("delivery = %+v, want succeeded after 1 attempt", delivery)
+	}
+	if len(recv.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(recv.requests))
+	}
+
+	req, body := recv.requests[0], recv.bodies[0]
+	if body != delivery.Payload {
+		t.Errorf("body = %q, want %q", body, delivery.Payload)
+	}
+	headers := map[string]string{
+		"Content-Type":       "application/json",
+		"X-Webhook-Event":    models.EventLinkCreated,
+		"X-Webhook-Id":       "event-1",
+		"X-Webhook-Delivery": "1",
+	}
+	for name, want := range headers {
+		if got := req.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	timestamp := req.Header.Get("X-Webhook-Timestamp")
+	if want := "sha256=" + Sign("s3cret", timestamp, body); req.Header.Get("X-Webhook-Signature") != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", req.Header.Get("X-Webhook-Signature"), want)
+	}
+	if Sign("other", timestamp, body) == Sign("s3cret", timestamp, body) {
+		t.Error("signatures with different secrets match")
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	setupTestDB(t)
+	recv := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	delivery := newDelivery(t, srv.URL, true)
+	d := &Dispatcher{Client: srv.Client()}
+
+	for attempt, status := range []int{http.StatusInternalServerError, http.StatusBadGateway} {
+		before := time.Now()
+		d.Deliver(&delivery)
+		if delivery.Status != models.DeliveryPending || delivery.Attempts != attempt+1 || delivery.LastStatusCode != status {
+			t.Fatalf("attempt %d: delivery = %+v", attempt+1, delivery)
+		}
+		wait := delivery.NextAttemptAt.Sub(before)
+		if want := backoff(attempt + 1); wait < want || wait > want+time.Second {
+			t.Errorf("attempt %d: next attempt in %v, want %v", attempt+1, wait, want)
+		}
+	}
+
+	// Not due yet
+	d.DispatchPending()
+	if len(recv.requests) != 2 {
+		t.Fatalf("receiver got %d requests before the retry was due, want 2", len(recv.requests))
+	}
+
+	models.DB.Model(&delivery).Update("next_attempt_at", time.Now().Add(-time.Second))
+	d.DispatchPending()
+	models.DB.First(&delivery, delivery.ID)
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 3 || delivery.LastError != "" {
+		t.Fatalf("delivery = %+v, want succeeded after 3 attempts", delivery)
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	setupTestDB(t)
+	recv := &receiver{statuses: []int{http.StatusInternalServerError}}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	delivery := newDelivery(t, srv.URL, true)
+	d := &Dispatcher{Client: srv.Client()}
+	for range maxAttempts {
+		d.Deliver(&delivery)
+	}
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != maxAttempts {
+		t.Fatalf("delivery = %+v, want failed after %d attempts", delivery, maxAttempts)
+	}
+}
+
+func TestDeliverInactiveWebhook(t *testing.T) {
+	setupTestDB(t)
+	recv := &receiver{statuses: []int{http.StatusOK}}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	delivery := newDelivery(t, srv.URL, false)
+	d := &Dispatcher{Client: srv.Client()}
+	d.Deliver(&delivery)
+
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 0 || delivery.LastError != "webhook is inactive" {
+		t.Fatalf("delivery = %+v, want failed without an attempt", delivery)
+	}
+	if len(recv.requests) != 0 {
+		t.Fatalf("receiver got %d requests, want none", len(recv.requests))
+	}
+}
+
+func TestDeliverRefusesPrivateAddresses(t *testing.T) {
+	setupTestDB(t)
+	recv := &receiver{statuses: []int{http.StatusOK}}
+	srv := httptest.NewServer(recv) // Listens on loopback
+	defer srv.Close()
+
+	delivery := newDelivery(t, srv.URL, true)
+	NewDispatcher().Deliver(&delivery)
+
+	if delivery.Status != models.DeliveryPending || !strings.Contains(delivery.LastError, "private address") {
+		t.Fatalf("delivery = %+v, want a private address error", delivery)
+	}
+	if len(recv.requests) != 0 {
+		t.Fatalf("receiver got %d requests, want none", len(recv.requests))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{10, 4*time.Hour + 16*time.Minute},
+		{11, maxBackoff},
+		{64, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}